@@ -174,6 +174,20 @@ func (mr *MockClientInterfaceMockRecorder) ChainID(ctx interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainID", reflect.TypeOf((*MockClientInterface)(nil).ChainID), ctx)
 }
 
+// Client mocks base method.
+func (m *MockClientInterface) Client() *rpc.Client {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Client")
+	ret0, _ := ret[0].(*rpc.Client)
+	return ret0
+}
+
+// Client indicates an expected call of Client.
+func (mr *MockClientInterfaceMockRecorder) Client() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Client", reflect.TypeOf((*MockClientInterface)(nil).Client))
+}
+
 // Close mocks base method.
 func (m *MockClientInterface) Close() {
 	m.ctrl.T.Helper()
@@ -485,6 +499,21 @@ func (mr *MockClientInterfaceMockRecorder) SubscribeFilterLogs(ctx, q, ch interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeFilterLogs", reflect.TypeOf((*MockClientInterface)(nil).SubscribeFilterLogs), ctx, q, ch)
 }
 
+// SubscribeNewHead mocks base method.
+func (m *MockClientInterface) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeNewHead", ctx, ch)
+	ret0, _ := ret[0].(ethereum.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeNewHead indicates an expected call of SubscribeNewHead.
+func (mr *MockClientInterfaceMockRecorder) SubscribeNewHead(ctx, ch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeNewHead", reflect.TypeOf((*MockClientInterface)(nil).SubscribeNewHead), ctx, ch)
+}
+
 // SuggestGasPrice mocks base method.
 func (m *MockClientInterface) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	m.ctrl.T.Helper()
@@ -545,3 +574,59 @@ func (mr *MockClientInterfaceMockRecorder) TransactionReceipt(ctx, txHash interf
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactionReceipt", reflect.TypeOf((*MockClientInterface)(nil).TransactionReceipt), ctx, txHash)
 }
+
+// MockRPCCaller is a mock of RPCCaller interface.
+type MockRPCCaller struct {
+	ctrl     *gomock.Controller
+	recorder *MockRPCCallerMockRecorder
+}
+
+// MockRPCCallerMockRecorder is the mock recorder for MockRPCCaller.
+type MockRPCCallerMockRecorder struct {
+	mock *MockRPCCaller
+}
+
+// NewMockRPCCaller creates a new mock instance.
+func NewMockRPCCaller(ctrl *gomock.Controller) *MockRPCCaller {
+	mock := &MockRPCCaller{ctrl: ctrl}
+	mock.recorder = &MockRPCCallerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRPCCaller) EXPECT() *MockRPCCallerMockRecorder {
+	return m.recorder
+}
+
+// BatchCallContext mocks base method.
+func (m *MockRPCCaller) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchCallContext", ctx, b)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchCallContext indicates an expected call of BatchCallContext.
+func (mr *MockRPCCallerMockRecorder) BatchCallContext(ctx, b interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCallContext", reflect.TypeOf((*MockRPCCaller)(nil).BatchCallContext), ctx, b)
+}
+
+// CallContext mocks base method.
+func (m *MockRPCCaller) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, result, method}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CallContext", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CallContext indicates an expected call of CallContext.
+func (mr *MockRPCCallerMockRecorder) CallContext(ctx, result, method interface{}, args ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, result, method}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CallContext", reflect.TypeOf((*MockRPCCaller)(nil).CallContext), varargs...)
+}
@@ -0,0 +1,60 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// CreateAccessList asks the node to generate the EIP-2930 access list for a transaction
+// using eth_createAccessList. It returns the access list and the gas used with it.
+func (m *Manager) CreateAccessList(ctx context.Context, tx utils.Transaction) (types.AccessList, uint64, error) {
+	if m.client == nil {
+		return nil, 0, utils.WrapError(utils.ErrClientNotStarted)
+	}
+
+	msg, err := newCallMsg(tx)
+	if err != nil {
+		return nil, 0, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	caller := rpcCaller(m.client)
+	if caller == nil {
+		return nil, 0, utils.WrapError(utils.ErrEVMFailedToCreateAccessList, errRPCClientUnavailable)
+	}
+
+	var result accessListResult
+	if err := caller.CallContext(ctx, &result, "eth_createAccessList", toCallArg(msg), "latest"); err != nil {
+		return nil, 0, wrapCallError(utils.ErrEVMFailedToCreateAccessList, err)
+	}
+	if result.Error != "" {
+		return nil, 0, utils.WrapError(fmt.Sprintf("%s: %s", utils.ErrEVMExecutionReverted, result.Error))
+	}
+	if result.AccessList == nil {
+		return types.AccessList{}, uint64(result.GasUsed), nil
+	}
+
+	return *result.AccessList, uint64(result.GasUsed), nil
+}
+
+// accessListResult is the response of eth_createAccessList.
+type accessListResult struct {
+	AccessList *types.AccessList `json:"accessList"`
+	Error      string            `json:"error,omitempty"`
+	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+}
+
+// FillAccessList generates the access list for a transaction and stores it in the
+// "accessList" payload field, so that the signer attaches it to the signed transaction.
+func (m *Manager) FillAccessList(ctx context.Context, tx utils.Transaction) error {
+	accessList, _, err := m.CreateAccessList(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	tx.SetPayload("accessList", accessList)
+	return nil
+}
@@ -0,0 +1,176 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	mock_signer "github.com/mselser95/blockchain/internal/mock/signer"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRPCRequest is a JSON-RPC request received by the stand-in server.
+type testRPCRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// testRPCResponse is a JSON-RPC response sent by the stand-in server.
+type testRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *testRPCError   `json:"error,omitempty"`
+}
+
+// testRPCError is a JSON-RPC error sent by the stand-in server.
+type testRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newTestRPCServer starts a stand-in JSON-RPC server that answers single and batch
+// requests with the given handler.
+func newTestRPCServer(t *testing.T, handler func(req testRPCRequest) (interface{}, *testRPCError)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body bytes.Buffer
+		_, err := body.ReadFrom(r.Body)
+		assert.NoError(t, err)
+
+		respond := func(req testRPCRequest) testRPCResponse {
+			result, rpcErr := handler(req)
+			return testRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if bytes.HasPrefix(bytes.TrimSpace(body.Bytes()), []byte("[")) {
+			var reqs []testRPCRequest
+			assert.NoError(t, json.Unmarshal(body.Bytes(), &reqs))
+			resps := make([]testRPCResponse, 0, len(reqs))
+			for _, req := range reqs {
+				resps = append(resps, respond(req))
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(resps))
+			return
+		}
+
+		var req testRPCRequest
+		assert.NoError(t, json.Unmarshal(body.Bytes(), &req))
+		assert.NoError(t, json.NewEncoder(w).Encode(respond(req)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestManager_CreateAccessList_Success tests that CreateAccessList calls eth_createAccessList and fills the payload.
+// go test -v -cover ./pkg/evm -run TestManager_CreateAccessList_Success
+func TestManager_CreateAccessList_Success(t *testing.T) {
+	contract := common.HexToAddress(generateRandomAddress().String())
+	slot := common.HexToHash("0x01")
+
+	server := newTestRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		assert.Equal(t, "eth_createAccessList", req.Method)
+
+		var arg map[string]interface{}
+		assert.NoError(t, json.Unmarshal(req.Params[0], &arg))
+		assert.Equal(t, contract.Hex(), common.HexToAddress(arg["to"].(string)).Hex())
+		assert.Equal(t, "0xa9059cbb", arg["input"])
+
+		return map[string]interface{}{
+			"accessList": []map[string]interface{}{
+				{"address": contract.Hex(), "storageKeys": []string{slot.Hex()}},
+			},
+			"gasUsed": "0x6d60",
+		}, nil
+	})
+
+	manager := evm.NewManager(server.URL, nil, &evm.EthClientFactory{}, utils.Ethereum).(*evm.Manager)
+	assert.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop(context.Background())
+
+	to, err := evm.NewAddress(contract.Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, generateRandomAddress(), to, big.NewInt(0), &txType, nil, nil, nil,
+		0, nil, nil, 0, []byte{0xa9, 0x05, 0x9c, 0xbb})
+
+	// Act
+	accessList, gasUsed, err := manager.CreateAccessList(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(28000), gasUsed)
+	assert.Equal(t, types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot}}}, accessList)
+
+	// Act
+	err = manager.FillAccessList(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, accessList, tx.Payload()["accessList"])
+}
+
+// TestManager_CreateAccessList_Reverted tests that CreateAccessList surfaces execution errors.
+// go test -v -cover ./pkg/evm -run TestManager_CreateAccessList_Reverted
+func TestManager_CreateAccessList_Reverted(t *testing.T) {
+	server := newTestRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		return map[string]interface{}{
+			"accessList": []interface{}{},
+			"gasUsed":    "0x5208",
+			"error":      "execution reverted",
+		}, nil
+	})
+
+	manager := evm.NewManager(server.URL, nil, &evm.EthClientFactory{}, utils.Ethereum).(*evm.Manager)
+	assert.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop(context.Background())
+
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(0), &txType,
+		nil, nil, nil, 0, nil, nil, 0, nil)
+
+	// Act
+	err := manager.FillAccessList(context.Background(), tx)
+
+	// Assert
+	assert.ErrorContains(t, err, utils.ErrEVMExecutionReverted)
+	_, hasAccessList := tx.Payload()["accessList"]
+	assert.False(t, hasAccessList)
+}
+
+// TestManager_CreateAccessList_NoRPCClient tests CreateAccessList when the client does not expose an RPC client.
+// go test -v -cover ./pkg/evm -run TestManager_CreateAccessList_NoRPCClient
+func TestManager_CreateAccessList_NoRPCClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+	mockClient.EXPECT().Client().Return(nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum).(*evm.Manager)
+	manager.Start(context.Background())
+
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(0), &txType,
+		nil, nil, nil, 0, nil, nil, 0, nil)
+
+	// Act
+	_, _, err := manager.CreateAccessList(context.Background(), tx)
+
+	// Assert
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToCreateAccessList)
+}
@@ -0,0 +1,364 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errRPCClientUnavailable is returned when the underlying client has no RPC connection to batch calls over.
+var errRPCClientUnavailable = errors.New("rpc client unavailable")
+
+// BatchingConfig configures how a BatchingClient coalesces calls.
+type BatchingConfig struct {
+	// Window is how long the first call of a batch waits for others to join it, 10ms by default.
+	Window time.Duration
+	// MaxBatchSize is the maximum number of calls sent in one batch request, 100 by default.
+	// A batch is sent as soon as it is full.
+	MaxBatchSize int
+	// Timeout bounds each batch request, 30s by default. Callers stop waiting when their own
+	// context is done.
+	Timeout time.Duration
+}
+
+// BatchingClient is a ClientInterface that coalesces the read calls made concurrently within
+// a short window into JSON-RPC batch requests, cutting round trips for bulk lookups. Calls
+// it does not batch go straight to the underlying client.
+//
+// The batched calls are ChainID, BlockNumber, HeaderByNumber, BalanceAt, NonceAt,
+// PendingNonceAt, CodeAt, StorageAt, CallContract and TransactionReceipt.
+//
+// Batches are sent as raw requests through the underlying client (see RPCCaller), so the
+// wrappers of this package below it apply to whole batches: a RateLimitedClient charges
+// every call in a batch, and a RetryClient or FailoverClient retries or fails over a batch
+// request that failed. Wrappers above it see every call on its own instead.
+type BatchingClient struct {
+	ClientInterface
+	config BatchingConfig
+
+	mu      sync.Mutex
+	pending []*batchCall
+	timer   *time.Timer
+}
+
+// batchCall is a call waiting to be sent in a batch. The response is decoded into the
+// caller's result only once the call is done, so callers that gave up never share memory
+// with a batch still in flight.
+type batchCall struct {
+	method string
+	args   []interface{}
+	result json.RawMessage
+	err    error
+	done   chan struct{}
+}
+
+// NewBatchingClient creates a new BatchingClient instance batching calls over the RPC
+// connection of the client, which is looked up again for every batch.
+func NewBatchingClient(client ClientInterface, config BatchingConfig) (*BatchingClient, error) {
+	if rpcCaller(client) == nil {
+		return nil, errRPCClientUnavailable
+	}
+	if config.Window <= 0 {
+		config.Window = 10 * time.Millisecond
+	}
+	if config.MaxBatchSize <= 0 {
+		config.MaxBatchSize = 100
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	return &BatchingClient{
+		ClientInterface: client,
+		config:          config,
+	}, nil
+}
+
+// ChainID retrieves the current chain ID.
+func (c *BatchingClient) ChainID(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := c.call(ctx, &result, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// BlockNumber returns the most recent block number.
+func (c *BatchingClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var result hexutil.Uint64
+	if err := c.call(ctx, &result, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If number is
+// nil, the latest known header is returned.
+func (c *BatchingClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var head *types.Header
+	if err := c.call(ctx, &head, "eth_getBlockByNumber", toBlockNumArg(number), false); err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, ethereum.NotFound
+	}
+	return head, nil
+}
+
+// BalanceAt returns the wei balance of the given account.
+func (c *BatchingClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result hexutil.Big
+	if err := c.call(ctx, &result, "eth_getBalance", account, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// NonceAt returns the account nonce of the given account.
+func (c *BatchingClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	var result hexutil.Uint64
+	if err := c.call(ctx, &result, "eth_getTransactionCount", account, toBlockNumArg(blockNumber)); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// PendingNonceAt returns the account nonce of the given account in the pending state.
+func (c *BatchingClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var result hexutil.Uint64
+	if err := c.call(ctx, &result, "eth_getTransactionCount", account, "pending"); err != nil {
+		return 0, err
+	}
+	return uint64(result), nil
+}
+
+// CodeAt returns the contract code of the given account.
+func (c *BatchingClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := c.call(ctx, &result, "eth_getCode", account, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StorageAt returns the value of key in the contract storage of the given account.
+func (c *BatchingClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := c.call(ctx, &result, "eth_getStorageAt", account, key, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CallContract executes a message call transaction without creating a transaction on the chain.
+func (c *BatchingClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result hexutil.Bytes
+	if err := c.call(ctx, &result, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TransactionReceipt returns the receipt of a transaction by transaction hash.
+func (c *BatchingClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	if err := c.call(ctx, &receipt, "eth_getTransactionReceipt", txHash); err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+// CallContext sends a raw JSON-RPC request to the underlying client, outside of any batch.
+func (c *BatchingClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	return caller.CallContext(ctx, result, method, args...)
+}
+
+// BatchCallContext sends raw JSON-RPC requests in their own batch to the underlying client.
+func (c *BatchingClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	return caller.BatchCallContext(ctx, b)
+}
+
+// Close sends the calls still waiting for their batch and closes the underlying client.
+func (c *BatchingClient) Close() {
+	c.mu.Lock()
+	calls := c.take()
+	c.mu.Unlock()
+
+	c.send(calls)
+	c.ClientInterface.Close()
+}
+
+// call queues a call for the next batch and waits for its result.
+func (c *BatchingClient) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	call := &batchCall{method: method, args: args, done: make(chan struct{})}
+
+	c.mu.Lock()
+	c.pending = append(c.pending, call)
+	var full []*batchCall
+	switch {
+	case len(c.pending) >= c.config.MaxBatchSize:
+		full = c.take()
+	case len(c.pending) == 1:
+		c.timer = time.AfterFunc(c.config.Window, c.flush)
+	}
+	c.mu.Unlock()
+
+	if full != nil {
+		go c.send(full)
+	}
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return call.err
+		}
+		return json.Unmarshal(call.result, result)
+	case <-ctx.Done():
+		c.cancel(call)
+		return ctx.Err()
+	}
+}
+
+// cancel removes a call whose caller gave up from the current batch, so it does not take
+// up a slot. Calls already sent are left to finish.
+func (c *BatchingClient) cancel(call *batchCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, pending := range c.pending {
+		if pending == call {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			break
+		}
+	}
+	if len(c.pending) == 0 {
+		c.take()
+	}
+}
+
+// flush sends the calls waiting for the current batch.
+func (c *BatchingClient) flush() {
+	c.mu.Lock()
+	calls := c.take()
+	c.mu.Unlock()
+
+	c.send(calls)
+}
+
+// take removes the calls waiting for the current batch and stops its timer. It must be
+// called with the lock held.
+func (c *BatchingClient) take() []*batchCall {
+	calls := c.pending
+	c.pending = nil
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	return calls
+}
+
+// send sends calls in one batch request and hands each caller its result. A lone call is
+// sent as a plain request. The RPC connection is looked up for every batch, so batches follow
+// the underlying client when it switches connections.
+func (c *BatchingClient) send(calls []*batchCall) {
+	if len(calls) == 0 {
+		return
+	}
+
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		for _, call := range calls {
+			call.err = errRPCClientUnavailable
+			close(call.done)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	if len(calls) == 1 {
+		call := calls[0]
+		call.err = caller.CallContext(ctx, &call.result, call.method, call.args...)
+		close(call.done)
+		return
+	}
+
+	elems := make([]rpc.BatchElem, len(calls))
+	for i, call := range calls {
+		elems[i] = rpc.BatchElem{Method: call.method, Args: call.args, Result: &call.result}
+	}
+	err := caller.BatchCallContext(ctx, elems)
+	for i, call := range calls {
+		call.err = elems[i].Error
+		if err != nil {
+			call.err = fmt.Errorf("batch request failed: %w", err)
+		}
+		close(call.done)
+	}
+}
+
+// toBlockNumArg encodes a block number the way ethclient does, with nil meaning the latest
+// block and negative numbers the special block tags.
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	if number.Sign() >= 0 {
+		return hexutil.EncodeBig(number)
+	}
+	if number.IsInt64() {
+		return rpc.BlockNumber(number.Int64()).String()
+	}
+	return fmt.Sprintf("<invalid %d>", number)
+}
+
+// BatchingClientFactory is a ClientFactory that wraps the clients of another factory in
+// BatchingClients.
+type BatchingClientFactory struct {
+	// Factory dials the underlying clients, an EthClientFactory when nil.
+	Factory ClientFactory
+	// Config configures how the clients batch calls.
+	Config BatchingConfig
+}
+
+// DialContext dials a new client and wraps it in a BatchingClient.
+func (f *BatchingClientFactory) DialContext(ctx context.Context, url string) (ClientInterface, error) {
+	factory := f.Factory
+	if factory == nil {
+		factory = &EthClientFactory{}
+	}
+
+	client, err := factory.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	batching, err := NewBatchingClient(client, f.Config)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return batching, nil
+}
@@ -0,0 +1,351 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRPCServer wraps a stand-in JSON-RPC server, counting the HTTP requests it receives
+// and the batch requests among them.
+type countingRPCServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	batches  atomic.Int32
+}
+
+// newCountingRPCServer starts a stand-in JSON-RPC server that counts its HTTP requests.
+func newCountingRPCServer(t *testing.T, handler func(req testRPCRequest) (interface{}, *testRPCError)) *countingRPCServer {
+	inner := newTestRPCServer(t, handler)
+	s := &countingRPCServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body bytes.Buffer
+		_, err := body.ReadFrom(r.Body)
+		assert.NoError(t, err)
+
+		s.requests.Add(1)
+		if bytes.HasPrefix(bytes.TrimSpace(body.Bytes()), []byte("[")) {
+			s.batches.Add(1)
+		}
+		r.Body = readCloser{bytes.NewReader(body.Bytes())}
+		inner.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Server.Close)
+	return s
+}
+
+// readCloser adds a no-op Close to a reader.
+type readCloser struct{ *bytes.Reader }
+
+func (readCloser) Close() error { return nil }
+
+// testReceiptJSON returns a minimal receipt in its JSON-RPC encoding.
+func testReceiptJSON(txHash string) map[string]interface{} {
+	return map[string]interface{}{
+		"transactionHash":   txHash,
+		"blockHash":         common.HexToHash("0xb1").Hex(),
+		"blockNumber":       "0x10",
+		"transactionIndex":  "0x0",
+		"status":            "0x1",
+		"cumulativeGasUsed": "0x5208",
+		"gasUsed":           "0x5208",
+		"logs":              []interface{}{},
+		"logsBloom":         hexutil.Encode(make([]byte, 256)),
+		"contractAddress":   nil,
+	}
+}
+
+// TestBatchingClient_CoalescesConcurrentCalls tests that concurrent calls are sent in one batch request.
+// go test -v -cover ./pkg/evm -run TestBatchingClient_CoalescesConcurrentCalls
+func TestBatchingClient_CoalescesConcurrentCalls(t *testing.T) {
+	missing := common.HexToHash("0xdead")
+	server := newCountingRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		switch req.Method {
+		case "eth_getBalance":
+			var account common.Address
+			assert.NoError(t, json.Unmarshal(req.Params[0], &account))
+			return hexutil.EncodeBig(new(big.Int).SetBytes(account.Bytes()[19:])), nil
+		case "eth_getTransactionReceipt":
+			var hash common.Hash
+			assert.NoError(t, json.Unmarshal(req.Params[0], &hash))
+			if hash == missing {
+				return json.RawMessage("null"), nil
+			}
+			return testReceiptJSON(hash.Hex()), nil
+		case "eth_call":
+			return nil, &testRPCError{Code: 3, Message: "execution reverted"}
+		}
+		return nil, &testRPCError{Code: -32601, Message: "method not found"}
+	})
+
+	factory := &evm.BatchingClientFactory{Config: evm.BatchingConfig{Window: 50 * time.Millisecond}}
+	client, err := factory.DialContext(context.Background(), server.URL)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// Act
+	var wg sync.WaitGroup
+	balances := make([]*big.Int, 5)
+	balanceErrs := make([]error, 5)
+	for i := range balances {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			account := common.BigToAddress(big.NewInt(int64(i + 1)))
+			balances[i], balanceErrs[i] = client.BalanceAt(context.Background(), account, nil)
+		}(i)
+	}
+	var receipt *types.Receipt
+	var receiptErr, missingErr, callErr error
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		receipt, receiptErr = client.TransactionReceipt(context.Background(), common.HexToHash("0xabc"))
+	}()
+	go func() {
+		defer wg.Done()
+		_, missingErr = client.TransactionReceipt(context.Background(), missing)
+	}()
+	go func() {
+		defer wg.Done()
+		to := common.HexToAddress("0x01")
+		_, callErr = client.CallContract(context.Background(), ethereum.CallMsg{To: &to}, nil)
+	}()
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, int32(1), server.requests.Load())
+	assert.Equal(t, int32(1), server.batches.Load())
+	for i := range balances {
+		assert.NoError(t, balanceErrs[i])
+		assert.Equal(t, big.NewInt(int64(i+1)), balances[i])
+	}
+	assert.NoError(t, receiptErr)
+	assert.Equal(t, uint64(16), receipt.BlockNumber.Uint64())
+	assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	assert.ErrorIs(t, missingErr, ethereum.NotFound)
+	assert.ErrorContains(t, callErr, "execution reverted")
+}
+
+// TestBatchingClient_MaxBatchSize tests that full batches are sent without waiting for the window.
+// go test -v -cover ./pkg/evm -run TestBatchingClient_MaxBatchSize
+func TestBatchingClient_MaxBatchSize(t *testing.T) {
+	server := newCountingRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		return "0x7", nil
+	})
+
+	factory := &evm.BatchingClientFactory{Config: evm.BatchingConfig{Window: time.Hour, MaxBatchSize: 3}}
+	client, err := factory.DialContext(context.Background(), server.URL)
+	assert.NoError(t, err)
+
+	// Act
+	var wg sync.WaitGroup
+	nonces := make([]uint64, 6)
+	for i := range nonces {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nonce, err := client.PendingNonceAt(context.Background(), common.BigToAddress(big.NewInt(int64(i))))
+			assert.NoError(t, err)
+			nonces[i] = nonce
+		}(i)
+	}
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, int32(2), server.batches.Load())
+	for _, nonce := range nonces {
+		assert.Equal(t, uint64(7), nonce)
+	}
+
+	// Calls still waiting are sent on Close
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := client.ChainID(ctx)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	client.Close()
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(3), server.requests.Load())
+	assert.Equal(t, int32(2), server.batches.Load()) // A lone call is sent as a plain request
+}
+
+// TestBatchingClient_Errors tests that transport failures and cancellations reach every caller.
+// go test -v -cover ./pkg/evm -run TestBatchingClient_Errors
+func TestBatchingClient_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	factory := &evm.BatchingClientFactory{Config: evm.BatchingConfig{Window: 20 * time.Millisecond}}
+	client, err := factory.DialContext(context.Background(), server.URL)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// Transport failures fail the whole batch
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			balance, err := client.BalanceAt(context.Background(), common.Address{}, nil)
+			assert.ErrorContains(t, err, "batch request failed")
+			assert.Nil(t, balance)
+		}()
+	}
+	wg.Wait()
+
+	// Callers stop waiting when their context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.HeaderByNumber(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestBatchingClient_CancelledCalls tests that calls given up within the window or in flight do not touch their results.
+// go test -v -race -cover ./pkg/evm -run TestBatchingClient_CancelledCalls
+func TestBatchingClient_CancelledCalls(t *testing.T) {
+	var handled atomic.Int32
+	server := newCountingRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		handled.Add(1)
+		if req.Method == "eth_chainId" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return "0x10", nil
+	})
+
+	factory := &evm.BatchingClientFactory{Config: evm.BatchingConfig{Window: 50 * time.Millisecond}}
+	client, err := factory.DialContext(context.Background(), server.URL)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// A call cancelled within the window leaves the batch
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			block, err := client.BlockNumber(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(16), block)
+		}()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.BlockNumber(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), server.batches.Load())
+	assert.Equal(t, int32(2), handled.Load())
+
+	// A call cancelled in flight gets its response after its caller is gone
+	ctx, cancel = context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+	_, err = client.ChainID(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Eventually(t, func() bool { return handled.Load() == 3 }, time.Second, time.Millisecond)
+}
+
+// TestBatchingClient_SwitchedConnection tests that every batch is sent over the current connection of the underlying client.
+// go test -v -cover ./pkg/evm -run TestBatchingClient_SwitchedConnection
+func TestBatchingClient_SwitchedConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	primary := newCountingRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		return "0x1", nil
+	})
+	backup := newCountingRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		return "0x2", nil
+	})
+	primaryRPC, err := rpc.DialContext(context.Background(), primary.URL)
+	assert.NoError(t, err)
+	defer primaryRPC.Close()
+	backupRPC, err := rpc.DialContext(context.Background(), backup.URL)
+	assert.NoError(t, err)
+	defer backupRPC.Close()
+
+	// The underlying client switches to another endpoint after the first batch
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	gomock.InOrder(
+		mockClient.EXPECT().Client().Return(primaryRPC).Times(2),
+		mockClient.EXPECT().Client().Return(backupRPC),
+		mockClient.EXPECT().Client().Return(nil),
+	)
+	client, err := evm.NewBatchingClient(mockClient, evm.BatchingConfig{Window: time.Millisecond})
+	assert.NoError(t, err)
+
+	// Act
+	first, firstErr := client.BlockNumber(context.Background())
+	second, secondErr := client.BlockNumber(context.Background())
+	_, thirdErr := client.BlockNumber(context.Background())
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.Equal(t, uint64(1), first)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, uint64(2), second)
+	assert.Equal(t, int32(1), primary.requests.Load())
+	assert.Equal(t, int32(1), backup.requests.Load())
+	assert.ErrorContains(t, thirdErr, "rpc client unavailable")
+}
+
+// TestBatchingClientFactory_DialContext tests wrapping the clients of another factory.
+// go test -v -cover ./pkg/evm -run TestBatchingClientFactory_DialContext
+func TestBatchingClientFactory_DialContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+
+	// Clients without an RPC connection cannot batch calls
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+	mockClient.EXPECT().Client().Return(nil)
+	mockClient.EXPECT().Close()
+
+	factory := &evm.BatchingClientFactory{Factory: mockClientFactory}
+	_, err := factory.DialContext(context.Background(), "http://localhost:8545")
+	assert.Error(t, err)
+
+	// Dial failures are returned
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(nil, fmt.Errorf("dial failed"))
+	_, err = factory.DialContext(context.Background(), "http://localhost:8545")
+	assert.ErrorContains(t, err, "dial failed")
+
+	// Managers use batching clients as a drop-in
+	server := newCountingRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		return "0x64", nil
+	})
+	manager := evm.NewManager(server.URL, nil, &evm.BatchingClientFactory{}, utils.Ethereum)
+	assert.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop(context.Background())
+
+	balance, err := manager.GetBalance(context.Background(), generateRandomAddress(), utils.Token{Type: utils.Native})
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(100), balance)
+}
@@ -0,0 +1,90 @@
+package evm
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+const (
+	// MaxBlobsPerTransaction is the maximum number of blobs a single transaction can carry.
+	MaxBlobsPerTransaction = params.MaxBlobGasPerBlock / params.BlobTxBlobGasPerBlob
+
+	// blobBytesPerFieldElement is the number of data bytes stored in each 32-byte field
+	// element. The leading byte is left zero so every element stays below the BLS modulus.
+	blobBytesPerFieldElement = 31
+
+	// MaxBlobDataSize is the maximum number of data bytes EncodeBlobs can store in one blob.
+	MaxBlobDataSize = params.BlobTxFieldElementsPerBlob * blobBytesPerFieldElement
+)
+
+// EncodeBlobs packs arbitrary data into blobs, 31 bytes per field element, so it can be
+// attached to a transaction through the "blobs" payload field.
+func EncodeBlobs(data []byte) ([]kzg4844.Blob, error) {
+	if len(data) == 0 {
+		return nil, errors.New("blob data is empty")
+	}
+
+	count := (len(data) + MaxBlobDataSize - 1) / MaxBlobDataSize
+	if count > MaxBlobsPerTransaction {
+		return nil, fmt.Errorf("blob data needs %d blobs, at most %d are allowed", count, MaxBlobsPerTransaction)
+	}
+
+	blobs := make([]kzg4844.Blob, count)
+	for i := range blobs {
+		chunk := data[i*MaxBlobDataSize:]
+		if len(chunk) > MaxBlobDataSize {
+			chunk = chunk[:MaxBlobDataSize]
+		}
+		for fe := 0; len(chunk) > 0; fe++ {
+			n := copy(blobs[i][fe*32+1:(fe+1)*32], chunk)
+			chunk = chunk[n:]
+		}
+	}
+
+	return blobs, nil
+}
+
+// newBlobSidecar computes the KZG commitments and proofs for the given blobs.
+func newBlobSidecar(blobs []kzg4844.Blob) (*types.BlobTxSidecar, error) {
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       blobs,
+		Commitments: make([]kzg4844.Commitment, len(blobs)),
+		Proofs:      make([]kzg4844.Proof, len(blobs)),
+	}
+
+	for i := range blobs {
+		commitment, err := kzg4844.BlobToCommitment(&blobs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute commitment for blob %d: %w", i, err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blobs[i], commitment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute proof for blob %d: %w", i, err)
+		}
+		sidecar.Commitments[i] = commitment
+		sidecar.Proofs[i] = proof
+	}
+
+	return sidecar, nil
+}
+
+// toUint256 converts a big integer field of a blob transaction to a uint256.
+func toUint256(name string, value *big.Int) (*uint256.Int, error) {
+	if value == nil {
+		return new(uint256.Int), nil
+	}
+	if value.Sign() < 0 {
+		return nil, fmt.Errorf("%s cannot be negative", name)
+	}
+	converted, overflow := uint256.FromBig(value)
+	if overflow {
+		return nil, fmt.Errorf("%s overflows 256 bits", name)
+	}
+	return converted, nil
+}
@@ -0,0 +1,43 @@
+package evm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/stretchr/testify/assert"
+)
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestEncodeBlobs_Success
+func TestEncodeBlobs_Success(t *testing.T) {
+	data := bytes.Repeat([]byte{0xff}, evm.MaxBlobDataSize+40)
+
+	blobs, err := evm.EncodeBlobs(data)
+	assert.NoError(t, err)
+	assert.Len(t, blobs, 2)
+
+	// Every field element keeps its leading byte zero and stores 31 data bytes
+	assert.Equal(t, byte(0x00), blobs[0][0])
+	assert.Equal(t, bytes.Repeat([]byte{0xff}, 31), blobs[0][1:32])
+	assert.Equal(t, byte(0x00), blobs[0][len(blobs[0])-32])
+
+	// The remainder spills into the second blob
+	assert.Equal(t, bytes.Repeat([]byte{0xff}, 31), blobs[1][1:32])
+	assert.Equal(t, bytes.Repeat([]byte{0xff}, 9), blobs[1][33:42])
+	assert.Equal(t, byte(0x00), blobs[1][42])
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestEncodeBlobs_TooLarge
+func TestEncodeBlobs_TooLarge(t *testing.T) {
+	data := make([]byte, evm.MaxBlobDataSize*evm.MaxBlobsPerTransaction+1)
+
+	blobs, err := evm.EncodeBlobs(data)
+	assert.Error(t, err)
+	assert.Nil(t, blobs)
+
+	blobs, err = evm.EncodeBlobs(nil)
+	assert.Error(t, err)
+	assert.Nil(t, blobs)
+}
@@ -0,0 +1,406 @@
+package evm
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CacheConfig configures what a CachingClient keeps and for how long.
+type CacheConfig struct {
+	// Size is the maximum number of entries kept, 1024 by default. The least recently used
+	// entries are evicted first.
+	Size int
+	// TTL is how long data tied to blocks that are not final yet is kept, 2s by default.
+	TTL time.Duration
+	// Finality is how many blocks a block must be behind the head to be treated as final,
+	// 64 by default.
+	Finality uint64
+}
+
+// cacheEntry is a cached call result.
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time // Zero for data that cannot change
+	block   uint64    // The block the data is tied to, if it expires
+}
+
+// CachingClient is a ClientInterface that caches the results of calls that cannot change in
+// a bounded LRU cache: the chain ID, blocks, receipts and state pinned by block hash, blocks
+// and receipts of final blocks, and the code of deployed contracts.
+//
+// Blocks and receipts that are not final yet, and the head block number, are kept for a
+// short TTL. When a block fetched by number has a different hash than the one seen before
+// at its height, the chain was reorganized and the entries tied to that height and above
+// are dropped. Every other call goes straight to the underlying client.
+//
+// Cached values are shared between callers, who must not modify them.
+type CachingClient struct {
+	ClientInterface
+	config CacheConfig
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List
+	canonical map[uint64]common.Hash // Hashes of the recent blocks seen, by number
+}
+
+// NewCachingClient creates a new CachingClient instance caching the calls of the client.
+func NewCachingClient(client ClientInterface, config CacheConfig) *CachingClient {
+	if config.Size <= 0 {
+		config.Size = 1024
+	}
+	if config.TTL <= 0 {
+		config.TTL = 2 * time.Second
+	}
+	if config.Finality == 0 {
+		config.Finality = 64
+	}
+
+	return &CachingClient{
+		ClientInterface: client,
+		config:          config,
+		entries:         map[string]*list.Element{},
+		lru:             list.New(),
+		canonical:       map[uint64]common.Hash{},
+	}
+}
+
+// CallContext sends a raw JSON-RPC request to the underlying client, without caching.
+func (c *CachingClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	return caller.CallContext(ctx, result, method, args...)
+}
+
+// BatchCallContext sends raw JSON-RPC requests in one batch to the underlying client,
+// without caching.
+func (c *CachingClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	return caller.BatchCallContext(ctx, b)
+}
+
+// ChainID retrieves the current chain ID.
+func (c *CachingClient) ChainID(ctx context.Context) (*big.Int, error) {
+	if value, ok := c.get("chainID"); ok {
+		return new(big.Int).Set(value.(*big.Int)), nil
+	}
+	chainID, err := c.ClientInterface.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.put("chainID", new(big.Int).Set(chainID), false, 0)
+	return chainID, nil
+}
+
+// NetworkID returns the network ID for this client.
+func (c *CachingClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	if value, ok := c.get("networkID"); ok {
+		return new(big.Int).Set(value.(*big.Int)), nil
+	}
+	networkID, err := c.ClientInterface.NetworkID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.put("networkID", new(big.Int).Set(networkID), false, 0)
+	return networkID, nil
+}
+
+// BlockNumber returns the most recent block number, kept for the TTL.
+func (c *CachingClient) BlockNumber(ctx context.Context) (uint64, error) {
+	if value, ok := c.get("blockNumber"); ok {
+		return value.(uint64), nil
+	}
+	number, err := c.ClientInterface.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.put("blockNumber", number, true, number)
+	return number, nil
+}
+
+// BlockByHash returns the given full block.
+func (c *CachingClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	key := "block:" + hash.Hex()
+	if value, ok := c.get(key); ok {
+		return value.(*types.Block), nil
+	}
+	block, err := c.ClientInterface.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, block, false, 0)
+	return block, nil
+}
+
+// BlockByNumber returns a block from the current canonical chain. If number is nil, the
+// latest known block is returned.
+func (c *CachingClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	key := "blockByNumber:" + toBlockNumArg(number)
+	if value, ok := c.get(key); ok {
+		return value.(*types.Block), nil
+	}
+	block, err := c.ClientInterface.BlockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	c.observe(block.NumberU64(), block.Hash())
+	c.put(key, block, !c.final(ctx, number), block.NumberU64())
+	c.put("block:"+block.Hash().Hex(), block, false, 0)
+	return block, nil
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If number is
+// nil, the latest known header is returned.
+func (c *CachingClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	key := "header:" + toBlockNumArg(number)
+	if value, ok := c.get(key); ok {
+		return value.(*types.Header), nil
+	}
+	header, err := c.ClientInterface.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	c.observe(header.Number.Uint64(), header.Hash())
+	c.put(key, header, !c.final(ctx, number), header.Number.Uint64())
+	return header, nil
+}
+
+// BlockReceipts returns the receipts of a given block number or hash.
+func (c *CachingClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	key := "blockReceipts:" + blockNrOrHash.String()
+	if value, ok := c.get(key); ok {
+		return value.([]*types.Receipt), nil
+	}
+	receipts, err := c.ClientInterface.BlockReceipts(ctx, blockNrOrHash)
+	if err != nil || len(receipts) == 0 {
+		return receipts, err
+	}
+
+	// Receipts pinned by hash cannot change, while block tags move with the chain
+	if _, ok := blockNrOrHash.Hash(); ok {
+		c.put(key, receipts, false, 0)
+		return receipts, nil
+	}
+	var number *big.Int
+	if nr, ok := blockNrOrHash.Number(); ok && nr >= 0 {
+		number = big.NewInt(nr.Int64())
+	}
+	block := receipts[0].BlockNumber.Uint64()
+	c.observe(block, receipts[0].BlockHash)
+	c.put(key, receipts, !c.final(ctx, number), block)
+	return receipts, nil
+}
+
+// TransactionReceipt returns the receipt of a transaction by transaction hash. Receipts of
+// transactions in blocks that are not final yet are kept for the TTL.
+func (c *CachingClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	key := "receipt:" + txHash.Hex()
+	if value, ok := c.get(key); ok {
+		return value.(*types.Receipt), nil
+	}
+	receipt, err := c.ClientInterface.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	c.observe(receipt.BlockNumber.Uint64(), receipt.BlockHash)
+	c.put(key, receipt, !c.final(ctx, receipt.BlockNumber), receipt.BlockNumber.Uint64())
+	return receipt, nil
+}
+
+// CodeAt returns the contract code of the given account. The code of deployed contracts is
+// cached, while accounts without code are not, since a contract may be deployed to them, and
+// neither is code in the pending state.
+func (c *CachingClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	key := fmt.Sprintf("code:%s:%s", account.Hex(), toBlockNumArg(blockNumber))
+	if value, ok := c.get(key); ok {
+		return value.([]byte), nil
+	}
+	code, err := c.ClientInterface.CodeAt(ctx, account, blockNumber)
+	if err != nil || len(code) == 0 || (blockNumber != nil && blockNumber.Sign() < 0) {
+		return code, err
+	}
+	c.put(key, code, false, 0)
+	return code, nil
+}
+
+// CodeAtHash returns the contract code of the given account at the given block hash.
+func (c *CachingClient) CodeAtHash(ctx context.Context, account common.Address, blockHash common.Hash) ([]byte, error) {
+	key := fmt.Sprintf("codeAtHash:%s:%s", account.Hex(), blockHash.Hex())
+	if value, ok := c.get(key); ok {
+		return value.([]byte), nil
+	}
+	code, err := c.ClientInterface.CodeAtHash(ctx, account, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, code, false, 0)
+	return code, nil
+}
+
+// BalanceAtHash returns the wei balance of the given account at the given block hash.
+func (c *CachingClient) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	key := fmt.Sprintf("balanceAtHash:%s:%s", account.Hex(), blockHash.Hex())
+	if value, ok := c.get(key); ok {
+		return new(big.Int).Set(value.(*big.Int)), nil
+	}
+	balance, err := c.ClientInterface.BalanceAtHash(ctx, account, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, new(big.Int).Set(balance), false, 0)
+	return balance, nil
+}
+
+// NonceAtHash returns the account nonce of the given account at the given block hash.
+func (c *CachingClient) NonceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (uint64, error) {
+	key := fmt.Sprintf("nonceAtHash:%s:%s", account.Hex(), blockHash.Hex())
+	if value, ok := c.get(key); ok {
+		return value.(uint64), nil
+	}
+	nonce, err := c.ClientInterface.NonceAtHash(ctx, account, blockHash)
+	if err != nil {
+		return 0, err
+	}
+	c.put(key, nonce, false, 0)
+	return nonce, nil
+}
+
+// StorageAtHash returns the value of key in the contract storage of the given account at the
+// given block hash.
+func (c *CachingClient) StorageAtHash(ctx context.Context, account common.Address, key common.Hash, blockHash common.Hash) ([]byte, error) {
+	cacheKey := fmt.Sprintf("storageAtHash:%s:%s:%s", account.Hex(), key.Hex(), blockHash.Hex())
+	if value, ok := c.get(cacheKey); ok {
+		return value.([]byte), nil
+	}
+	value, err := c.ClientInterface.StorageAtHash(ctx, account, key, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	c.put(cacheKey, value, false, 0)
+	return value, nil
+}
+
+// get returns the cached value of key, marking it as recently used.
+func (c *CachingClient) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return entry.value, true
+}
+
+// put caches the value of key, for the TTL when it is tied to a block that is not final,
+// evicting the least recently used entries beyond the size.
+func (c *CachingClient) put(key string, value interface{}, expires bool, block uint64) {
+	entry := &cacheEntry{key: key, value: value, block: block}
+	if expires {
+		entry.expires = time.Now().Add(c.config.TTL)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.config.Size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove drops a cached entry. It must be called with the lock held.
+func (c *CachingClient) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+// final reports whether a block is final, at least Finality blocks behind the head. The
+// latest block and the other block tags are never final.
+func (c *CachingClient) final(ctx context.Context, number *big.Int) bool {
+	if number == nil || number.Sign() < 0 {
+		return false
+	}
+	head, err := c.BlockNumber(ctx)
+	if err != nil {
+		return false
+	}
+	return number.Uint64()+c.config.Finality <= head
+}
+
+// observe records the hash of the block at a height. A hash different from the one seen
+// before means the chain was reorganized, so the entries that expire and are tied to that
+// height or above are dropped.
+func (c *CachingClient) observe(number uint64, hash common.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seen, ok := c.canonical[number]; ok && seen != hash {
+		for element := c.lru.Front(); element != nil; {
+			next := element.Next()
+			if entry := element.Value.(*cacheEntry); !entry.expires.IsZero() && entry.block >= number {
+				c.remove(element)
+			}
+			element = next
+		}
+		for height := range c.canonical {
+			if height >= number {
+				delete(c.canonical, height)
+			}
+		}
+	}
+	c.canonical[number] = hash
+
+	// Only recent heights can be reorganized
+	for height := range c.canonical {
+		if height+c.config.Finality < number {
+			delete(c.canonical, height)
+		}
+	}
+}
+
+// CachingClientFactory is a ClientFactory that wraps the clients of another factory in
+// CachingClients.
+type CachingClientFactory struct {
+	// Factory dials the underlying clients, an EthClientFactory when nil.
+	Factory ClientFactory
+	// Config configures what the clients cache.
+	Config CacheConfig
+}
+
+// DialContext dials a new client and wraps it in a CachingClient.
+func (f *CachingClientFactory) DialContext(ctx context.Context, url string) (ClientInterface, error) {
+	factory := f.Factory
+	if factory == nil {
+		factory = &EthClientFactory{}
+	}
+
+	client, err := factory.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return NewCachingClient(client, f.Config), nil
+}
@@ -0,0 +1,244 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/stretchr/testify/assert"
+)
+
+// testHeader returns a header at the given number, with extra data to tell forks apart.
+func testHeader(number int64, fork string) *types.Header {
+	return &types.Header{Number: big.NewInt(number), Extra: []byte(fork), Difficulty: big.NewInt(0)}
+}
+
+// testReceipt returns a receipt of a transaction included in the given block.
+func testReceipt(txHash common.Hash, header *types.Header) *types.Receipt {
+	return &types.Receipt{
+		TxHash:      txHash,
+		BlockNumber: header.Number,
+		BlockHash:   header.Hash(),
+		Status:      types.ReceiptStatusSuccessful,
+	}
+}
+
+// TestCachingClient_ImmutableData tests that data that cannot change is fetched once.
+// go test -v -cover ./pkg/evm -run TestCachingClient_ImmutableData
+func TestCachingClient_ImmutableData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	client := evm.NewCachingClient(mockClient, evm.CacheConfig{})
+
+	contract := common.HexToAddress(generateRandomAddress().String())
+	account := common.HexToAddress(generateRandomAddress().String())
+	block := types.NewBlockWithHeader(testHeader(100, "a"))
+
+	mockClient.EXPECT().ChainID(gomock.Any()).Return(big.NewInt(1), nil)
+	mockClient.EXPECT().BlockByHash(gomock.Any(), block.Hash()).Return(block, nil)
+	mockClient.EXPECT().CodeAt(gomock.Any(), contract, nil).Return([]byte{0x60, 0x80}, nil)
+	mockClient.EXPECT().BalanceAtHash(gomock.Any(), account, block.Hash()).Return(big.NewInt(42), nil)
+
+	for i := 0; i < 3; i++ {
+		chainID, err := client.ChainID(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(1), chainID)
+
+		fetched, err := client.BlockByHash(context.Background(), block.Hash())
+		assert.NoError(t, err)
+		assert.Equal(t, block.Hash(), fetched.Hash())
+
+		code, err := client.CodeAt(context.Background(), contract, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x60, 0x80}, code)
+
+		balance, err := client.BalanceAtHash(context.Background(), account, block.Hash())
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(42), balance)
+	}
+
+	// Returned values cannot corrupt the cache
+	chainID, _ := client.ChainID(context.Background())
+	chainID.SetInt64(5)
+	chainID, _ = client.ChainID(context.Background())
+	assert.Equal(t, big.NewInt(1), chainID)
+
+	// Accounts without code, pending state and errors are not cached
+	mockClient.EXPECT().CodeAt(gomock.Any(), account, nil).Return(nil, nil).Times(2)
+	mockClient.EXPECT().CodeAt(gomock.Any(), contract, big.NewInt(int64(rpc.PendingBlockNumber))).Return([]byte{0x60}, nil).Times(2)
+	mockClient.EXPECT().NetworkID(gomock.Any()).Return(nil, errors.New("connection refused"))
+	mockClient.EXPECT().NetworkID(gomock.Any()).Return(big.NewInt(1), nil)
+	for i := 0; i < 2; i++ {
+		code, err := client.CodeAt(context.Background(), account, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, code)
+		_, err = client.CodeAt(context.Background(), contract, big.NewInt(int64(rpc.PendingBlockNumber)))
+		assert.NoError(t, err)
+	}
+	_, err := client.NetworkID(context.Background())
+	assert.Error(t, err)
+	networkID, err := client.NetworkID(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1), networkID)
+
+	// Other calls go straight to the underlying client
+	mockClient.EXPECT().BalanceAt(gomock.Any(), account, nil).Return(big.NewInt(1), nil)
+	mockClient.EXPECT().BalanceAt(gomock.Any(), account, nil).Return(big.NewInt(2), nil)
+	balance, err := client.BalanceAt(context.Background(), account, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1), balance)
+	balance, err = client.BalanceAt(context.Background(), account, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(2), balance)
+}
+
+// TestCachingClient_Finality tests that data tied to blocks that are not final expires.
+// go test -v -cover ./pkg/evm -run TestCachingClient_Finality
+func TestCachingClient_Finality(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	client := evm.NewCachingClient(mockClient, evm.CacheConfig{TTL: 50 * time.Millisecond, Finality: 10})
+
+	finalTx, recentTx := common.HexToHash("0x01"), common.HexToHash("0x02")
+	finalHeader, recentHeader := testHeader(900, "a"), testHeader(995, "a")
+
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(1000), nil).Times(2)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), finalTx).Return(testReceipt(finalTx, finalHeader), nil)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), recentTx).Return(testReceipt(recentTx, recentHeader), nil).Times(2)
+	mockClient.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(900)).Return(types.NewBlockWithHeader(finalHeader), nil)
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), nil).Return(recentHeader, nil).Times(2)
+
+	fetch := func() {
+		receipt, err := client.TransactionReceipt(context.Background(), finalTx)
+		assert.NoError(t, err)
+		assert.Equal(t, finalHeader.Hash(), receipt.BlockHash)
+		receipt, err = client.TransactionReceipt(context.Background(), recentTx)
+		assert.NoError(t, err)
+		assert.Equal(t, recentHeader.Hash(), receipt.BlockHash)
+		block, err := client.BlockByNumber(context.Background(), big.NewInt(900))
+		assert.NoError(t, err)
+		assert.Equal(t, finalHeader.Hash(), block.Hash())
+		header, err := client.HeaderByNumber(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, recentHeader.Hash(), header.Hash())
+	}
+
+	// Within the TTL everything is cached
+	fetch()
+	fetch()
+
+	// After it only data of final blocks is
+	time.Sleep(60 * time.Millisecond)
+	fetch()
+
+	// Blocks fetched by number are cached by hash too
+	block, err := client.BlockByHash(context.Background(), finalHeader.Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(900), block.NumberU64())
+}
+
+// TestCachingClient_Reorg tests that data tied to reorganized blocks is dropped.
+// go test -v -cover ./pkg/evm -run TestCachingClient_Reorg
+func TestCachingClient_Reorg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	client := evm.NewCachingClient(mockClient, evm.CacheConfig{TTL: time.Hour, Finality: 10})
+
+	txHash := common.HexToHash("0x01")
+	original, replacement := testHeader(995, "a"), testHeader(995, "b")
+
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(1000), nil).AnyTimes()
+	gomock.InOrder(
+		mockClient.EXPECT().TransactionReceipt(gomock.Any(), txHash).Return(testReceipt(txHash, original), nil),
+		mockClient.EXPECT().TransactionReceipt(gomock.Any(), txHash).Return(testReceipt(txHash, replacement), nil),
+	)
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(995)).Return(original, nil)
+	mockClient.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(995)).Return(types.NewBlockWithHeader(replacement), nil)
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(995)).Return(replacement, nil)
+
+	receipt, err := client.TransactionReceipt(context.Background(), txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, original.Hash(), receipt.BlockHash)
+	header, err := client.HeaderByNumber(context.Background(), big.NewInt(995))
+	assert.NoError(t, err)
+	assert.Equal(t, original.Hash(), header.Hash())
+
+	// Another block at the same height drops the receipt and the header
+	block, err := client.BlockByNumber(context.Background(), big.NewInt(995))
+	assert.NoError(t, err)
+	assert.Equal(t, replacement.Hash(), block.Hash())
+
+	receipt, err = client.TransactionReceipt(context.Background(), txHash)
+	assert.NoError(t, err)
+	assert.Equal(t, replacement.Hash(), receipt.BlockHash)
+	header, err = client.HeaderByNumber(context.Background(), big.NewInt(995))
+	assert.NoError(t, err)
+	assert.Equal(t, replacement.Hash(), header.Hash())
+}
+
+// TestCachingClient_Size tests that the least recently used entries are evicted first.
+// go test -v -cover ./pkg/evm -run TestCachingClient_Size
+func TestCachingClient_Size(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	client := evm.NewCachingClient(mockClient, evm.CacheConfig{Size: 2})
+
+	blocks := []*types.Block{
+		types.NewBlockWithHeader(testHeader(1, "a")),
+		types.NewBlockWithHeader(testHeader(2, "a")),
+		types.NewBlockWithHeader(testHeader(3, "a")),
+	}
+	mockClient.EXPECT().BlockByHash(gomock.Any(), blocks[0].Hash()).Return(blocks[0], nil)
+	mockClient.EXPECT().BlockByHash(gomock.Any(), blocks[1].Hash()).Return(blocks[1], nil).Times(2)
+	mockClient.EXPECT().BlockByHash(gomock.Any(), blocks[2].Hash()).Return(blocks[2], nil)
+
+	for _, i := range []int{0, 1, 0, 2, 0, 1} {
+		block, err := client.BlockByHash(context.Background(), blocks[i].Hash())
+		assert.NoError(t, err)
+		assert.Equal(t, blocks[i].Hash(), block.Hash())
+	}
+}
+
+// TestCachingClientFactory_DialContext tests wrapping the clients of another factory.
+// go test -v -cover ./pkg/evm -run TestCachingClientFactory_DialContext
+func TestCachingClientFactory_DialContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	factory := &evm.CachingClientFactory{Factory: mockClientFactory}
+
+	// Dial failures are returned
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(nil, errors.New("dial failed"))
+	_, err := factory.DialContext(context.Background(), "http://localhost:8545")
+	assert.ErrorContains(t, err, "dial failed")
+
+	// Clients share nothing with each other
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil).Times(2)
+	mockClient.EXPECT().ChainID(gomock.Any()).Return(big.NewInt(1), nil).Times(2)
+	for i := 0; i < 2; i++ {
+		client, err := factory.DialContext(context.Background(), "http://localhost:8545")
+		assert.NoError(t, err)
+		_, err = client.ChainID(context.Background())
+		assert.NoError(t, err)
+		_, err = client.ChainID(context.Background())
+		assert.NoError(t, err)
+	}
+}
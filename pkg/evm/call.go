@@ -0,0 +1,161 @@
+package evm
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// newCallMsg builds an ethereum.CallMsg from the fields and payload of a transaction.
+func newCallMsg(tx utils.Transaction) (ethereum.CallMsg, error) {
+	msg := ethereum.CallMsg{}
+	if tx == nil {
+		return msg, errors.New("missing transaction")
+	}
+
+	if from := tx.From(); from != nil && from.String() != "" {
+		msg.From = common.HexToAddress(from.String())
+	}
+	if to := tx.To(); to != nil && to.String() != "" {
+		toAddress := common.HexToAddress(to.String())
+		msg.To = &toAddress
+	}
+	if tx.Amount() != nil {
+		msg.Value = tx.Amount()
+	}
+
+	payload := tx.Payload()
+	if data, ok := payload["data"]; ok {
+		if msg.Data, ok = data.([]byte); !ok {
+			return msg, errors.New("payload 'data' must be a []byte")
+		}
+	}
+	if gasLimit, ok := payload["gasLimit"]; ok {
+		if msg.Gas, ok = gasLimit.(uint64); !ok {
+			return msg, errors.New("payload 'gasLimit' must be uint64")
+		}
+	}
+	if gasPrice, ok := payload["gasPrice"]; ok {
+		if msg.GasPrice, ok = gasPrice.(*big.Int); !ok {
+			return msg, errors.New("payload 'gasPrice' must be *big.Int")
+		}
+	}
+	if maxFee, ok := payload["maxFeePerGas"]; ok {
+		if msg.GasFeeCap, ok = maxFee.(*big.Int); !ok {
+			return msg, errors.New("payload 'maxFeePerGas' must be *big.Int")
+		}
+	}
+	if maxTip, ok := payload["maxPriorityFeePerGas"]; ok {
+		if msg.GasTipCap, ok = maxTip.(*big.Int); !ok {
+			return msg, errors.New("payload 'maxPriorityFeePerGas' must be *big.Int")
+		}
+	}
+	if accessList, ok := payload["accessList"]; ok {
+		if msg.AccessList, ok = accessList.(types.AccessList); !ok {
+			return msg, errors.New("payload 'accessList' must be types.AccessList")
+		}
+	}
+
+	// Nodes reject calls that mix legacy and dynamic fee fields
+	if msg.GasPrice != nil && (msg.GasFeeCap != nil || msg.GasTipCap != nil) {
+		msg.GasPrice = nil
+	}
+
+	return msg, nil
+}
+
+// toCallArg converts a call message into the JSON-RPC transaction call object.
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
+	if msg.BlobGasFeeCap != nil {
+		arg["maxFeePerBlobGas"] = (*hexutil.Big)(msg.BlobGasFeeCap)
+	}
+	if msg.BlobHashes != nil {
+		arg["blobVersionedHashes"] = msg.BlobHashes
+	}
+	return arg
+}
+
+// wrapCallError wraps an error returned by a call or gas estimation, decoding the
+// revert reason when the node reports that the execution reverted.
+func wrapCallError(contextMsg string, err error) error {
+	reason, reverted := revertReason(err)
+	if !reverted {
+		return utils.WrapError(contextMsg, err)
+	}
+	if reason == "" {
+		return utils.WrapError(utils.ErrEVMExecutionReverted, err)
+	}
+	return utils.WrapError(fmt.Sprintf("%s: %s", utils.ErrEVMExecutionReverted, reason), err)
+}
+
+// revertReason reports whether err describes a reverted execution and, if the node
+// returned revert data, the decoded reason.
+func revertReason(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := revertData(dataErr.ErrorData()); ok {
+			if reason, err := abi.UnpackRevert(data); err == nil {
+				return reason, true
+			}
+			// Custom errors cannot be decoded without the contract ABI
+			return "0x" + hex.EncodeToString(data), true
+		}
+	}
+
+	return "", strings.Contains(err.Error(), "execution reverted")
+}
+
+// revertData extracts the raw revert bytes from the data field of a JSON-RPC error.
+func revertData(data interface{}) ([]byte, bool) {
+	switch d := data.(type) {
+	case string:
+		decoded, err := hex.DecodeString(strings.TrimPrefix(d, "0x"))
+		if err != nil || len(decoded) == 0 {
+			return nil, false
+		}
+		return decoded, true
+	case []byte:
+		return d, len(d) > 0
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,196 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+const (
+	// defaultPollInterval is how often WaitForConfirmation polls when the transport does not
+	// support new head subscriptions.
+	defaultPollInterval = 2 * time.Second
+
+	// defaultDroppedTimeout is how long a transaction whose nonce is still unused must be
+	// unknown to the node before WaitForConfirmation considers it dropped.
+	defaultDroppedTimeout = time.Minute
+)
+
+// WaitForConfirmation blocks until the transaction has the given number of confirmations and
+// returns its details. A transaction included in the latest block has one confirmation, and
+// zero confirmations are treated as one.
+//
+// New heads are followed through a subscription when the transport supports it, and polled
+// otherwise. If the receipt moves to another block because of a reorg, the confirmations are
+// counted again from the new block. A transaction without a receipt whose nonce is used by
+// another transaction, or that the node does not know for longer than the dropped timeout
+// (see WithDroppedTimeout), fails with ErrEVMTransactionDropped. A transaction that reverted
+// fails with ErrEVMTransactionReverted, along with its details.
+func (m *Manager) WaitForConfirmation(ctx context.Context, txID string, confirmations uint64) (*utils.TransactionDetails, error) {
+	if m.client == nil {
+		return nil, utils.WrapError(utils.ErrClientNotStarted)
+	}
+	if confirmations == 0 {
+		confirmations = 1
+	}
+
+	waiter := &confirmationWaiter{
+		manager:       m,
+		hash:          common.HexToHash(txID),
+		confirmations: confirmations,
+	}
+
+	ticker := time.NewTicker(m.pollInterval())
+	defer ticker.Stop()
+
+	// Follow new heads when possible, and fall back to polling otherwise
+	heads := make(chan *types.Header, 16)
+	var tick <-chan time.Time
+	var subErr <-chan error
+	sub, err := m.client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		tick = ticker.C
+	} else {
+		defer sub.Unsubscribe()
+		subErr = sub.Err()
+	}
+
+	for {
+		if done, details, err := waiter.check(ctx); done {
+			return details, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, utils.WrapError(utils.ErrEVMFailedToWaitForConfirmation, ctx.Err())
+		case <-heads:
+		case <-tick:
+		case <-subErr:
+			// The subscription was lost, keep waiting by polling
+			subErr = nil
+			tick = ticker.C
+		}
+	}
+}
+
+// confirmationWaiter holds the state of a WaitForConfirmation call between checks.
+type confirmationWaiter struct {
+	manager       *Manager
+	hash          common.Hash
+	confirmations uint64
+
+	tx           *types.Transaction // Transaction as last seen by the node
+	missingSince time.Time          // When the node stopped knowing the transaction, zero while it does
+}
+
+// check reports whether waiting is over, along with the result. Node errors are treated as
+// transient and retried on the next check.
+func (w *confirmationWaiter) check(ctx context.Context) (bool, *utils.TransactionDetails, error) {
+	client := w.manager.client
+
+	receipt, err := client.TransactionReceipt(ctx, w.hash)
+	if errors.Is(err, ethereum.NotFound) {
+		// Not mined yet, or reorged out of the chain
+		return w.checkDropped(ctx)
+	}
+	if err != nil {
+		return false, nil, nil
+	}
+	w.missingSince = time.Time{}
+
+	// Confirmations are counted from the block of the latest receipt, so they start over
+	// when a reorg moves the transaction to another block
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return false, nil, nil
+	}
+	blockNumber := receipt.BlockNumber.Uint64()
+	if head < blockNumber || head-blockNumber+1 < w.confirmations {
+		return false, nil, nil
+	}
+
+	// A receipt whose block hash no longer matches the canonical chain is being reorged
+	header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil || header.Hash() != receipt.BlockHash {
+		return false, nil, nil
+	}
+
+	details, err := w.manager.GetTransactionDetails(ctx, w.hash.Hex())
+	if err != nil {
+		return false, nil, nil
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return true, details, utils.WrapError(utils.ErrEVMTransactionReverted)
+	}
+	return true, details, nil
+}
+
+// checkDropped reports whether a transaction without a receipt was dropped by the node.
+func (w *confirmationWaiter) checkDropped(ctx context.Context) (bool, *utils.TransactionDetails, error) {
+	client := w.manager.client
+
+	tx, _, err := client.TransactionByHash(ctx, w.hash)
+	switch {
+	case err == nil:
+		w.tx = tx
+		w.missingSince = time.Time{}
+		return false, nil, nil
+	case !errors.Is(err, ethereum.NotFound):
+		return false, nil, nil
+	}
+	if w.missingSince.IsZero() {
+		w.missingSince = time.Now()
+	}
+
+	// Once the nonce of a transaction the node no longer knows is used, it can only have been
+	// mined if it has a receipt, which is checked again since it may have been mined meanwhile
+	if w.tx != nil {
+		sender, err := types.Sender(types.LatestSignerForChainID(w.tx.ChainId()), w.tx)
+		if err == nil {
+			nonce, err := client.NonceAt(ctx, sender, nil)
+			if err == nil && nonce > w.tx.Nonce() {
+				_, err := client.TransactionReceipt(ctx, w.hash)
+				if errors.Is(err, ethereum.NotFound) {
+					return true, nil, w.droppedError()
+				}
+				return false, nil, nil
+			}
+		}
+	}
+
+	// Otherwise the node may just be slow to see it, so it is given up on only after a while
+	if time.Since(w.missingSince) >= w.manager.droppedTimeout() {
+		return true, nil, w.droppedError()
+	}
+	return false, nil, nil
+}
+
+// droppedError returns the dropped error, naming the replacement when one was sent.
+func (w *confirmationWaiter) droppedError() error {
+	if replacement, ok := w.manager.ReplacedBy(w.hash.Hex()); ok {
+		return utils.WrapError(utils.ErrEVMTransactionDropped, fmt.Errorf("replaced by %s", replacement))
+	}
+	return utils.WrapError(utils.ErrEVMTransactionDropped)
+}
+
+// droppedTimeout returns how long a transaction whose nonce is unused may be unknown to the node.
+func (m *Manager) droppedTimeout() time.Duration {
+	if m.droppedAfter > 0 {
+		return m.droppedAfter
+	}
+	return defaultDroppedTimeout
+}
+
+// pollInterval returns the interval between polls when new heads cannot be subscribed to.
+func (m *Manager) pollInterval() time.Duration {
+	if m.pollEvery > 0 {
+		return m.pollEvery
+	}
+	return defaultPollInterval
+}
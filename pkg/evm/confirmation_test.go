@@ -0,0 +1,257 @@
+package evm_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSignedTransfer signs a legacy transfer with the given nonce.
+func newSignedTransfer(t *testing.T, privateKey *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tx, err := types.SignNewTx(privateKey, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: big.NewInt(1),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1000),
+	})
+	assert.NoError(t, err)
+	return tx
+}
+
+// newMinedReceipt returns the receipt of a transaction mined in the block with the given header.
+func newMinedReceipt(header *types.Header, status uint64) *types.Receipt {
+	return &types.Receipt{
+		Status:      status,
+		GasUsed:     21000,
+		BlockHash:   header.Hash(),
+		BlockNumber: header.Number,
+	}
+}
+
+// TestManager_WaitForConfirmation_Reorg tests that the confirmations restart when a reorg moves the transaction.
+// go test -v -cover ./pkg/evm -run TestManager_WaitForConfirmation_Reorg
+func TestManager_WaitForConfirmation_Reorg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond))
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx := newSignedTransfer(t, privateKey, 1)
+
+	headerA := &types.Header{Number: big.NewInt(10), Extra: []byte("a")}
+	headerB := &types.Header{Number: big.NewInt(12), Extra: []byte("b")}
+
+	mockClient.EXPECT().SubscribeNewHead(gomock.Any(), gomock.Any()).Return(nil, rpc.ErrNotificationsUnsupported)
+
+	// Not mined yet, then mined in block 10, then reorged into block 12
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(nil, ethereum.NotFound).Times(1)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, true, nil).Times(1)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(newMinedReceipt(headerA, types.ReceiptStatusSuccessful), nil).Times(1)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(11), nil).Times(1)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(newMinedReceipt(headerB, types.ReceiptStatusSuccessful), nil).Times(3)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(12), nil).Times(1)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(14), nil).Times(1)
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(12)).Return(headerB, nil)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil).Times(1)
+
+	// Act
+	details, err := manager.WaitForConfirmation(context.Background(), tx.Hash().Hex(), 3)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, utils.Confirmed, details.Status)
+	assert.Equal(t, uint64(12), details.BlockNumber)
+}
+
+// TestManager_WaitForConfirmation_StaleReceipt tests that a receipt whose block left the canonical chain is not confirmed.
+// go test -v -cover ./pkg/evm -run TestManager_WaitForConfirmation_StaleReceipt
+func TestManager_WaitForConfirmation_StaleReceipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond))
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx := newSignedTransfer(t, privateKey, 1)
+
+	stale := &types.Header{Number: big.NewInt(10), Extra: []byte("stale")}
+	canonical := &types.Header{Number: big.NewInt(10), Extra: []byte("canonical")}
+
+	mockClient.EXPECT().SubscribeNewHead(gomock.Any(), gomock.Any()).Return(nil, rpc.ErrNotificationsUnsupported)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(newMinedReceipt(stale, types.ReceiptStatusSuccessful), nil).Times(1)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(newMinedReceipt(canonical, types.ReceiptStatusSuccessful), nil).Times(2)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(10), nil).Times(2)
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(10)).Return(canonical, nil).Times(2)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+
+	// Act
+	details, err := manager.WaitForConfirmation(context.Background(), tx.Hash().Hex(), 0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), details.BlockNumber)
+}
+
+// TestManager_WaitForConfirmation_Reverted tests that new heads are followed and reverted transactions are reported.
+// go test -v -cover ./pkg/evm -run TestManager_WaitForConfirmation_Reverted
+func TestManager_WaitForConfirmation_Reverted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	// Without a poll interval, waiting only progresses through new heads
+	manager := evm.NewManager("http://localhost:8545", nil, mockClientFactory, utils.Ethereum).(*evm.Manager)
+	manager.Start(context.Background())
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx := newSignedTransfer(t, privateKey, 1)
+	header := &types.Header{Number: big.NewInt(5)}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	mockClient.EXPECT().SubscribeNewHead(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ch <- header
+			}()
+			return event.NewSubscription(func(unsubscribed <-chan struct{}) error {
+				<-unsubscribed
+				return nil
+			}), nil
+		})
+
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(nil, ethereum.NotFound).Times(1)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, true, nil).Times(1)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(newMinedReceipt(header, types.ReceiptStatusFailed), nil).Times(2)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(5), nil)
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(5)).Return(header, nil)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil).Times(1)
+
+	// Act
+	details, err := manager.WaitForConfirmation(context.Background(), tx.Hash().Hex(), 1)
+
+	// Assert
+	assert.ErrorContains(t, err, utils.ErrEVMTransactionReverted)
+	assert.NotNil(t, details)
+	assert.Equal(t, utils.Failed, details.Status)
+}
+
+// TestManager_WaitForConfirmation_Dropped tests that transactions the node forgot about are reported as dropped.
+// go test -v -cover ./pkg/evm -run TestManager_WaitForConfirmation_Dropped
+func TestManager_WaitForConfirmation_Dropped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond), evm.WithDroppedTimeout(20*time.Millisecond))
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx := newSignedTransfer(t, privateKey, 7)
+
+	mockClient.EXPECT().SubscribeNewHead(gomock.Any(), gomock.Any()).Return(nil, rpc.ErrNotificationsUnsupported).Times(2)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(nil, ethereum.NotFound).AnyTimes()
+
+	// The transaction was seen pending, then its nonce was used by another transaction
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, true, nil).Times(1)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(nil, false, ethereum.NotFound).Times(1)
+	mockClient.EXPECT().NonceAt(gomock.Any(), crypto.PubkeyToAddress(privateKey.PublicKey), nil).Return(uint64(8), nil)
+
+	details, err := manager.WaitForConfirmation(context.Background(), tx.Hash().Hex(), 1)
+	assert.Nil(t, details)
+	assert.ErrorContains(t, err, utils.ErrEVMTransactionDropped)
+
+	// The transaction is never known to the node, so it is given up on after the timeout
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(nil, false, ethereum.NotFound).MinTimes(3)
+
+	start := time.Now()
+	details, err = manager.WaitForConfirmation(context.Background(), tx.Hash().Hex(), 1)
+	assert.Nil(t, details)
+	assert.ErrorContains(t, err, utils.ErrEVMTransactionDropped)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+// TestManager_WaitForConfirmation_MinedWhileChecking tests that a transaction mined between the receipt and nonce checks is not dropped.
+// go test -v -cover ./pkg/evm -run TestManager_WaitForConfirmation_MinedWhileChecking
+func TestManager_WaitForConfirmation_MinedWhileChecking(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond))
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx := newSignedTransfer(t, privateKey, 7)
+	header := &types.Header{Number: big.NewInt(10)}
+
+	mockClient.EXPECT().SubscribeNewHead(gomock.Any(), gomock.Any()).Return(nil, rpc.ErrNotificationsUnsupported)
+
+	// Seen pending, then gone from the pool with its nonce used, because it was just mined
+	gomock.InOrder(
+		mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(nil, ethereum.NotFound),
+		mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, true, nil),
+		mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(nil, ethereum.NotFound),
+		mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(nil, false, ethereum.NotFound),
+		mockClient.EXPECT().NonceAt(gomock.Any(), crypto.PubkeyToAddress(privateKey.PublicKey), nil).Return(uint64(8), nil),
+		mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(newMinedReceipt(header, types.ReceiptStatusSuccessful), nil).Times(3),
+	)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(10), nil)
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), big.NewInt(10)).Return(header, nil)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+
+	// Act
+	details, err := manager.WaitForConfirmation(context.Background(), tx.Hash().Hex(), 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, utils.Confirmed, details.Status)
+}
+
+// TestManager_WaitForConfirmation_Canceled tests that waiting stops when the context is done.
+// go test -v -cover ./pkg/evm -run TestManager_WaitForConfirmation_Canceled
+func TestManager_WaitForConfirmation_Canceled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond))
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx := newSignedTransfer(t, privateKey, 1)
+
+	mockClient.EXPECT().SubscribeNewHead(gomock.Any(), gomock.Any()).Return(nil, errors.New("notifications not supported"))
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(nil, ethereum.NotFound).AnyTimes()
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, true, nil).AnyTimes()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	details, err := manager.WaitForConfirmation(ctx, tx.Hash().Hex(), 1)
+
+	// Assert
+	assert.Nil(t, details)
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToWaitForConfirmation)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
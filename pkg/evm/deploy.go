@@ -0,0 +1,100 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// NewDeployTransaction creates a transaction deploying a contract, with the ABI-encoded
+// constructor arguments appended to its bytecode. Zero gas fields and a nil nonce are left for
+// PrepareTransaction to fill in, while a given nonce is kept, including 0.
+func NewDeployTransaction(
+	from utils.Address,
+	amount *big.Int,
+	bytecode []byte,
+	constructorArgs []byte,
+	gasLimit uint64,
+	gasPrice *big.Int,
+	chainId *big.Int,
+	nonce *uint64,
+) utils.Transaction {
+	if amount == nil {
+		amount = big.NewInt(0)
+	}
+
+	data := make([]byte, 0, len(bytecode)+len(constructorArgs))
+	data = append(data, bytecode...)
+	data = append(data, constructorArgs...)
+
+	txType := utils.Deploy
+	tx := NewTransaction(
+		nil, from, nil, amount, &txType, nil, nil, nil,
+		gasLimit, gasPrice, chainId, 0, data,
+	)
+	// NewTransaction skips a zero nonce, which is the first nonce of an account
+	if nonce != nil {
+		tx.SetPayload("nonce", *nonce)
+	}
+	return tx
+}
+
+// EncodeConstructorArgs ABI-encodes constructor arguments for the contract ABI given as JSON.
+func EncodeConstructorArgs(abiJSON string, args ...interface{}) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Pack("", args...)
+}
+
+// DeployContract sends a deploy transaction and returns its hash along with the address the
+// contract is created at. The address is final once the transaction is mined, and is also
+// reported as ContractAddress by GetTransactionDetails.
+func (m *Manager) DeployContract(ctx context.Context, tx utils.Transaction) (string, utils.Address, error) {
+	if tx == nil || tx.Type() == nil || *tx.Type() != utils.Deploy {
+		return "", nil, utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("not a deploy transaction"))
+	}
+
+	hash, err := m.SendTransaction(ctx, tx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The nonce is known once the transaction was signed
+	nonce, _ := tx.Payload()["nonce"].(uint64)
+	address, err := CreateAddress(tx.From(), nonce)
+	if err != nil {
+		return "", nil, utils.WrapError(utils.ErrEVMInvalidAddress, err)
+	}
+
+	return hash, address, nil
+}
+
+// CreateAddress returns the address of a contract deployed by the sender with the given
+// nonce through a deploy transaction or the CREATE opcode.
+func CreateAddress(sender utils.Address, nonce uint64) (utils.Address, error) {
+	if sender == nil || !common.IsHexAddress(sender.String()) {
+		return nil, errors.New("invalid EVM address")
+	}
+
+	address := crypto.CreateAddress(common.HexToAddress(sender.String()), nonce)
+	return &Address{address: address, network: utils.Blockchain(sender.Network())}, nil
+}
+
+// CreateAddress2 returns the address of a contract deployed by the deployer contract through
+// the CREATE2 opcode, with the given salt and init code (bytecode with constructor arguments).
+func CreateAddress2(deployer utils.Address, salt [32]byte, initCode []byte) (utils.Address, error) {
+	if deployer == nil || !common.IsHexAddress(deployer.String()) {
+		return nil, errors.New("invalid EVM address")
+	}
+
+	address := crypto.CreateAddress2(common.HexToAddress(deployer.String()), salt, crypto.Keccak256(initCode))
+	return &Address{address: address, network: utils.Blockchain(deployer.Network())}, nil
+}
@@ -0,0 +1,182 @@
+package evm_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConstructorAbi = `[{"inputs":[{"name":"supply","type":"uint256"}],"stateMutability":"nonpayable","type":"constructor"}]`
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestCreateAddress
+func TestCreateAddress(t *testing.T) {
+	sender, err := evm.NewAddress("0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0", utils.Ethereum)
+	assert.NoError(t, err)
+
+	address, err := evm.CreateAddress(sender, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0xcd234a471b72ba2f1ccf0a70fcaba648a5eecd8d").Hex(), address.String())
+	assert.Equal(t, string(utils.Ethereum), address.Network())
+
+	address, err = evm.CreateAddress(sender, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress("0x343c43a37d37dff08ae8c4a11544c718abb4fcf8").Hex(), address.String())
+
+	_, err = evm.CreateAddress(nil, 0)
+	assert.Error(t, err)
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestCreateAddress2
+func TestCreateAddress2(t *testing.T) {
+	// Example 0 of EIP-1014
+	deployer, err := evm.NewAddress("0x0000000000000000000000000000000000000000", utils.Ethereum)
+	assert.NoError(t, err)
+
+	address, err := evm.CreateAddress2(deployer, [32]byte{}, []byte{0x00})
+	assert.NoError(t, err)
+	assert.Equal(t, "0x4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38", address.String())
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestBaseTransaction_Validate_Deploy
+func TestBaseTransaction_Validate_Deploy(t *testing.T) {
+	from := generateRandomAddress()
+	bytecode := []byte{0x60, 0x80, 0x60, 0x40}
+
+	nonce := uint64(1)
+	tx := evm.NewDeployTransaction(from, nil, bytecode, nil, 100000, big.NewInt(1), big.NewInt(1), &nonce)
+	assert.NoError(t, tx.Validate())
+	assert.Nil(t, tx.To())
+	assert.Equal(t, 0, tx.Amount().Sign())
+
+	// A nonce of 0 is kept, and no nonce is left for PrepareTransaction
+	nonce = 0
+	tx = evm.NewDeployTransaction(from, nil, bytecode, nil, 100000, big.NewInt(1), big.NewInt(1), &nonce)
+	assert.Equal(t, uint64(0), tx.Payload()["nonce"])
+	tx = evm.NewDeployTransaction(from, nil, bytecode, nil, 100000, big.NewInt(1), big.NewInt(1), nil)
+	assert.NotContains(t, tx.Payload(), "nonce")
+
+	// Deployments need bytecode
+	tx = evm.NewDeployTransaction(from, nil, nil, nil, 100000, big.NewInt(1), big.NewInt(1), &nonce)
+	assert.EqualError(t, tx.Validate(), "deploy transaction requires the contract bytecode in payload 'data'")
+
+	// Deployments cannot have a recipient
+	txType := utils.Deploy
+	tx = evm.NewTransaction(nil, from, generateRandomAddress(), big.NewInt(0), &txType, nil, nil, nil,
+		100000, big.NewInt(1), big.NewInt(1), 1, bytecode)
+	assert.EqualError(t, tx.Validate(), "deploy transaction cannot have a recipient")
+}
+
+// TestManager_DeployContract tests that a deployment is signed without recipient and returns the contract address.
+// go test -v -cover ./pkg/evm -run TestManager_DeployContract
+func TestManager_DeployContract(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	signer, err := evm.NewPrivateKeySigner(fmt.Sprintf("%x", crypto.FromECDSA(privateKey)))
+	assert.NoError(t, err)
+	sender := crypto.PubkeyToAddress(privateKey.PublicKey)
+	from, err := evm.NewAddress(sender.Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	manager := evm.NewManager("http://localhost:8545", signer, mockClientFactory, utils.Ethereum).(*evm.Manager)
+	manager.Start(context.Background())
+
+	bytecode := []byte{0x60, 0x80, 0x60, 0x40}
+	args, err := evm.EncodeConstructorArgs(testConstructorAbi, big.NewInt(1000))
+	assert.NoError(t, err)
+	assert.Equal(t, common.LeftPadBytes(big.NewInt(1000).Bytes(), 32), args)
+
+	var sent *types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = tx
+			return nil
+		})
+
+	// Act
+	nonce := uint64(5)
+	tx := evm.NewDeployTransaction(from, nil, bytecode, args, 200000, big.NewInt(1), big.NewInt(1), &nonce)
+	hash, address, err := manager.DeployContract(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sent.Hash().Hex(), hash)
+	assert.Nil(t, sent.To())
+	assert.Equal(t, append(bytecode, args...), sent.Data())
+	assert.Equal(t, crypto.CreateAddress(sender, 5).Hex(), address.String())
+
+	// The first deployment of an account is at nonce 0
+	nonce = 0
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).Return(nil)
+	_, address, err = manager.DeployContract(context.Background(),
+		evm.NewDeployTransaction(from, nil, bytecode, args, 200000, big.NewInt(1), big.NewInt(1), &nonce))
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.CreateAddress(sender, 0).Hex(), address.String())
+
+	// Only deploy transactions can be deployed
+	txType := utils.Transfer
+	_, _, err = manager.DeployContract(context.Background(), evm.NewTransaction(nil, from, from, big.NewInt(1), &txType,
+		nil, nil, nil, 21000, big.NewInt(1), big.NewInt(1), 1, nil))
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidTransaction)
+}
+
+// TestManager_GetTransactionDetails_ContractCreation tests the details of a contract creation transaction.
+// go test -v -cover ./pkg/evm -run TestManager_GetTransactionDetails_ContractCreation
+func TestManager_GetTransactionDetails_ContractCreation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", nil, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx, err := types.SignNewTx(privateKey, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{
+		Nonce:    3,
+		GasPrice: big.NewInt(1),
+		Gas:      200000,
+		Data:     []byte{0x60, 0x80, 0x60, 0x40},
+	})
+	assert.NoError(t, err)
+	contract := crypto.CreateAddress(crypto.PubkeyToAddress(privateKey.PublicKey), 3)
+
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(&types.Receipt{
+		Status:          types.ReceiptStatusSuccessful,
+		BlockNumber:     big.NewInt(9),
+		GasUsed:         150000,
+		ContractAddress: contract,
+	}, nil)
+
+	// Act
+	details, err := manager.GetTransactionDetails(context.Background(), tx.Hash().Hex())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Nil(t, details.To)
+	assert.Equal(t, contract.Hex(), details.ContractAddress.String())
+	assert.Equal(t, utils.Confirmed, details.Status)
+}
@@ -0,0 +1,198 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// BalanceOfBatch returns the balance of each account for the ERC1155 token at the same
+// position, in a single call. All tokens must belong to the same contract.
+func (m *Manager) BalanceOfBatch(ctx context.Context, accounts []utils.Address, tokens []utils.Token) ([]*big.Int, error) {
+	if len(accounts) == 0 || len(accounts) != len(tokens) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidToken, errors.New("accounts and tokens must be non-empty and of the same length"))
+	}
+
+	owners := make([]common.Address, len(accounts))
+	for i, account := range accounts {
+		if account == nil || !common.IsHexAddress(account.String()) {
+			return nil, utils.WrapError(utils.ErrEVMInvalidAddress, fmt.Errorf("invalid account at index %d", i))
+		}
+		owners[i] = common.HexToAddress(account.String())
+	}
+	_, ids, err := erc1155IDs(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []*big.Int
+	if err := m.callToken(ctx, tokens[0], utils.ERC1155, erc1155Abi, "balanceOfBatch", &balances, owners, ids); err != nil {
+		return nil, err
+	}
+	if len(balances) != len(accounts) {
+		return nil, utils.WrapError(utils.ErrEVMFailedToReadToken,
+			fmt.Errorf("expected %d balances, got %d", len(accounts), len(balances)))
+	}
+	return balances, nil
+}
+
+// TransferERC1155 transfers an amount of an ERC1155 token from the sender to the recipient
+// with safeTransferFrom. Since the caller never sees the transaction, it is prepared before
+// signing whether or not WithAutoPrepare is set; build it with NewERC1155TransferTransaction
+// and send it with SendTransaction to control the fees or nonce.
+func (m *Manager) TransferERC1155(
+	ctx context.Context,
+	from utils.Address,
+	token utils.Token,
+	to utils.Address,
+	amount *big.Int,
+) (string, error) {
+	tx, err := NewERC1155TransferTransaction(from, token, to, amount, nil)
+	if err != nil {
+		return "", err
+	}
+	return m.sendTransaction(ctx, tx, true)
+}
+
+// BatchTransferERC1155 transfers amounts of several ERC1155 tokens of the same contract from
+// the sender to the recipient with safeBatchTransferFrom. Like TransferERC1155, it always
+// prepares the transaction it builds.
+func (m *Manager) BatchTransferERC1155(
+	ctx context.Context,
+	from utils.Address,
+	tokens []utils.Token,
+	to utils.Address,
+	amounts []*big.Int,
+) (string, error) {
+	tx, err := NewERC1155BatchTransferTransaction(from, tokens, to, amounts, nil)
+	if err != nil {
+		return "", err
+	}
+	return m.sendTransaction(ctx, tx, true)
+}
+
+// NewERC1155TransferTransaction creates a transaction calling safeTransferFrom on the token
+// contract to move an amount of the token from the sender to the recipient. The data is
+// passed on to the recipient's onERC1155Received hook. Gas fields, nonce and chain ID are
+// left for PrepareTransaction to fill in.
+func NewERC1155TransferTransaction(
+	from utils.Address,
+	token utils.Token,
+	to utils.Address,
+	amount *big.Int,
+	data []byte,
+) (utils.Transaction, error) {
+	if token.ID == nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidToken, errors.New("missing token ID"))
+	}
+	if amount == nil || amount.Sign() < 0 {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("invalid amount"))
+	}
+	return newERC1155Transaction(from, token, to, "safeTransferFrom", token.ID, amount, data)
+}
+
+// NewERC1155BatchTransferTransaction creates a transaction calling safeBatchTransferFrom on
+// the token contract to move an amount of each token from the sender to the recipient.
+func NewERC1155BatchTransferTransaction(
+	from utils.Address,
+	tokens []utils.Token,
+	to utils.Address,
+	amounts []*big.Int,
+	data []byte,
+) (utils.Transaction, error) {
+	if len(tokens) == 0 || len(tokens) != len(amounts) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("tokens and amounts must be non-empty and of the same length"))
+	}
+	for i, amount := range amounts {
+		if amount == nil || amount.Sign() < 0 {
+			return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, fmt.Errorf("invalid amount at index %d", i))
+		}
+	}
+	_, ids, err := erc1155IDs(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return newERC1155Transaction(from, tokens[0], to, "safeBatchTransferFrom", ids, amounts, data)
+}
+
+// newERC1155Transaction creates a transaction calling a transfer method of an ERC1155 contract.
+func newERC1155Transaction(
+	from utils.Address,
+	token utils.Token,
+	to utils.Address,
+	method string,
+	ids interface{},
+	amounts interface{},
+	data []byte,
+) (utils.Transaction, error) {
+	if from == nil || !common.IsHexAddress(from.String()) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid sender address"))
+	}
+	if to == nil || !common.IsHexAddress(to.String()) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid recipient address"))
+	}
+	if _, err := tokenAddress(token, utils.ERC1155); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(erc1155Abi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC1155 ABI: %w", err)
+	}
+	input, err := parsedABI.Pack(method, common.HexToAddress(from.String()), common.HexToAddress(to.String()), ids, amounts, data)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	txType := utils.ContractCall
+	return NewTransaction(nil, from, *token.Address, big.NewInt(0), &txType, nil, nil, nil, 0, nil, nil, 0, input), nil
+}
+
+// getERC1155Balance returns the amount of the ERC1155 token the address owns.
+func (m *Manager) getERC1155Balance(ctx context.Context, address utils.Address, token utils.Token) (*big.Int, error) {
+	if address == nil || !common.IsHexAddress(address.String()) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidAddress)
+	}
+	if token.ID == nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidToken, errors.New("missing token ID"))
+	}
+
+	var balance *big.Int
+	err := m.callToken(ctx, token, utils.ERC1155, erc1155Abi, "balanceOf", &balance,
+		common.HexToAddress(address.String()), token.ID)
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// erc1155IDs returns the contract and IDs of ERC1155 tokens that all belong to the same contract.
+func erc1155IDs(tokens []utils.Token) (common.Address, []*big.Int, error) {
+	var contract common.Address
+	ids := make([]*big.Int, len(tokens))
+	for i, token := range tokens {
+		address, err := tokenAddress(token, utils.ERC1155)
+		if err != nil {
+			return common.Address{}, nil, err
+		}
+		if i == 0 {
+			contract = address
+		} else if address != contract {
+			return common.Address{}, nil, utils.WrapError(utils.ErrEVMInvalidToken, errors.New("tokens belong to different contracts"))
+		}
+		if token.ID == nil {
+			return common.Address{}, nil, utils.WrapError(utils.ErrEVMInvalidToken, fmt.Errorf("missing token ID at index %d", i))
+		}
+		ids[i] = token.ID
+	}
+	return contract, ids, nil
+}
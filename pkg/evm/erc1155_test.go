@@ -0,0 +1,245 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+
+// newERC1155Tokens returns ERC1155 tokens with the given IDs in one contract at a random address.
+func newERC1155Tokens(ids ...int64) []utils.Token {
+	address := generateRandomAddress()
+	tokens := make([]utils.Token, len(ids))
+	for i, id := range ids {
+		tokens[i] = utils.Token{Type: utils.ERC1155, Address: &address, ID: big.NewInt(id)}
+	}
+	return tokens
+}
+
+// unpackArgs ABI-decodes values of the given types.
+func unpackArgs(t *testing.T, typeNames []string, data []byte) []interface{} {
+	var args abi.Arguments
+	for _, typeName := range typeNames {
+		argType, err := abi.NewType(typeName, "", nil)
+		assert.NoError(t, err)
+		args = append(args, abi.Argument{Type: argType})
+	}
+	values, err := args.Unpack(data)
+	assert.NoError(t, err)
+	return values
+}
+
+// TestManager_GetBalance_ERC1155 tests the GetBalance method for an ERC1155 token.
+// go test -v -cover ./pkg/evm -run TestManager_GetBalance_ERC1155
+func TestManager_GetBalance_ERC1155(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, owner := newTestManager(t, ctrl)
+	token := newERC1155Tokens(3)[0]
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+			args := unpackArgs(t, []string{"address", "uint256"}, msg.Data[4:])
+			assert.Equal(t, common.HexToAddress(owner.String()), args[0])
+			assert.Equal(t, big.NewInt(3), args[1])
+			return tokenContract(t, map[string][]byte{
+				"balanceOf(address,uint256)": packArgs(t, []string{"uint256"}, big.NewInt(25)),
+			})(ctx, msg, block)
+		})
+
+	// Act
+	balance, err := manager.GetBalance(context.Background(), owner, token)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(25), balance)
+
+	// A token ID is required
+	_, err = manager.GetBalance(context.Background(), owner, utils.Token{Type: utils.ERC1155, Address: token.Address})
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+}
+
+// TestManager_BalanceOfBatch tests reading the balances of several accounts and tokens in one call.
+// go test -v -cover ./pkg/evm -run TestManager_BalanceOfBatch
+func TestManager_BalanceOfBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, owner := newTestManager(t, ctrl)
+	tokens := newERC1155Tokens(1, 2)
+	other := generateRandomAddress()
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+			args := unpackArgs(t, []string{"address[]", "uint256[]"}, msg.Data[4:])
+			assert.Equal(t, []common.Address{common.HexToAddress(owner.String()), common.HexToAddress(other.String())}, args[0])
+			assert.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, args[1])
+			return tokenContract(t, map[string][]byte{
+				"balanceOfBatch(address[],uint256[])": packArgs(t, []string{"uint256[]"}, []*big.Int{big.NewInt(10), big.NewInt(0)}),
+			})(ctx, msg, block)
+		})
+
+	// Act
+	balances, err := manager.BalanceOfBatch(context.Background(), []utils.Address{owner, other}, tokens)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+	assert.Equal(t, 0, balances[0].Cmp(big.NewInt(10)))
+	assert.Equal(t, 0, balances[1].Sign())
+
+	// Tokens must belong to the same contract
+	_, err = manager.BalanceOfBatch(context.Background(), []utils.Address{owner, other},
+		[]utils.Token{tokens[0], newERC1155Tokens(2)[0]})
+	assert.ErrorContains(t, err, "tokens belong to different contracts")
+
+	// Accounts and tokens must match
+	_, err = manager.BalanceOfBatch(context.Background(), []utils.Address{owner}, tokens)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+}
+
+// TestNewERC1155TransferTransactions tests building safeTransferFrom and safeBatchTransferFrom transactions.
+// go test -v -cover ./pkg/evm -run TestNewERC1155TransferTransactions
+func TestNewERC1155TransferTransactions(t *testing.T) {
+	from := generateRandomAddress()
+	to := generateRandomAddress()
+	tokens := newERC1155Tokens(4, 5)
+
+	// Single transfer
+	tx, err := evm.NewERC1155TransferTransaction(from, tokens[0], to, big.NewInt(3), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, (*tokens[0].Address).String(), tx.To().String())
+
+	data := tx.Payload()["data"].([]byte)
+	assert.Equal(t, crypto.Keccak256([]byte("safeTransferFrom(address,address,uint256,uint256,bytes)"))[:4], data[:4])
+	args := unpackArgs(t, []string{"address", "address", "uint256", "uint256", "bytes"}, data[4:])
+	assert.Equal(t, common.HexToAddress(from.String()), args[0])
+	assert.Equal(t, common.HexToAddress(to.String()), args[1])
+	assert.Equal(t, big.NewInt(4), args[2])
+	assert.Equal(t, big.NewInt(3), args[3])
+	assert.Equal(t, []byte{}, args[4])
+
+	// Batch transfer
+	tx, err = evm.NewERC1155BatchTransferTransaction(from, tokens, to, []*big.Int{big.NewInt(1), big.NewInt(2)}, []byte{0xff})
+	assert.NoError(t, err)
+
+	data = tx.Payload()["data"].([]byte)
+	assert.Equal(t, crypto.Keccak256([]byte("safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)"))[:4], data[:4])
+	args = unpackArgs(t, []string{"address", "address", "uint256[]", "uint256[]", "bytes"}, data[4:])
+	assert.Equal(t, []*big.Int{big.NewInt(4), big.NewInt(5)}, args[2])
+	assert.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, args[3])
+	assert.Equal(t, []byte{0xff}, args[4])
+
+	// Invalid transfers
+	_, err = evm.NewERC1155BatchTransferTransaction(from, tokens, to, []*big.Int{big.NewInt(1)}, nil)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidTransaction)
+	_, err = evm.NewERC1155TransferTransaction(from, tokens[0], to, big.NewInt(-1), nil)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidTransaction)
+	_, err = evm.NewERC1155TransferTransaction(from, newERC721Token(4), to, big.NewInt(1), nil)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+}
+
+// TestManager_BatchTransferERC1155 tests that a batch transfer is prepared and sent to the token contract.
+// go test -v -cover ./pkg/evm -run TestManager_BatchTransferERC1155
+func TestManager_BatchTransferERC1155(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl)
+	tokens := newERC1155Tokens(1, 2)
+
+	expectPrepare(mockClient)
+	var sent *types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = tx
+			return nil
+		})
+
+	// Act
+	hash, err := manager.BatchTransferERC1155(context.Background(), from, tokens, generateRandomAddress(),
+		[]*big.Int{big.NewInt(1), big.NewInt(1)})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sent.Hash().Hex(), hash)
+	assert.Equal(t, (*tokens[0].Address).String(), sent.To().Hex())
+	assert.Equal(t, crypto.Keccak256([]byte("safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)"))[:4], sent.Data()[:4])
+}
+
+// TestManager_GetTransactionDetails_ERC1155Events tests that ERC1155 transfer logs are decoded.
+// go test -v -cover ./pkg/evm -run TestManager_GetTransactionDetails_ERC1155Events
+func TestManager_GetTransactionDetails_ERC1155Events(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", nil, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	tx, _ := generateSignedTransaction(t, testEventTokenAddr, big.NewInt(0))
+	indexed := []common.Hash{
+		common.BytesToHash(testEventOperator.Bytes()),
+		common.BytesToHash(testEventFromAddr.Bytes()),
+		common.BytesToHash(testEventToAddr.Bytes()),
+	}
+	receipt := &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		BlockNumber: big.NewInt(5),
+		GasUsed:     80000,
+		Logs: []*types.Log{
+			{
+				Address: testEventTokenAddr,
+				Topics:  append([]common.Hash{transferSingleTopic}, indexed...),
+				Data:    packArgs(t, []string{"uint256", "uint256"}, big.NewInt(7), big.NewInt(2)),
+				Index:   0,
+			},
+			{
+				Address: testEventTokenAddr,
+				Topics:  append([]common.Hash{transferBatchTopic}, indexed...),
+				Data: packArgs(t, []string{"uint256[]", "uint256[]"},
+					[]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(10), big.NewInt(20)}),
+				Index: 1,
+			},
+		},
+	}
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(receipt, nil)
+
+	// Act
+	details, err := manager.GetTransactionDetails(context.Background(), tx.Hash().Hex())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, details.DecodedEvents, 2)
+
+	single := details.DecodedEvents[0]
+	assert.Equal(t, "TransferSingle", single.Name)
+	assert.Equal(t, testEventOperator, single.Params["operator"])
+	assert.Equal(t, testEventFromAddr, single.Params["from"])
+	assert.Equal(t, testEventToAddr, single.Params["to"])
+	assert.Equal(t, big.NewInt(7), single.Params["id"])
+	assert.Equal(t, big.NewInt(2), single.Params["value"])
+
+	batch := details.DecodedEvents[1]
+	assert.Equal(t, "TransferBatch", batch.Name)
+	assert.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, batch.Params["ids"])
+	assert.Equal(t, []*big.Int{big.NewInt(10), big.NewInt(20)}, batch.Params["values"])
+}
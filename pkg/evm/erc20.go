@@ -0,0 +1,194 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// TransferERC20 transfers an amount of an ERC20 token from the sender to the recipient.
+// The transfer is simulated first, so transfers the token would revert or reject are never
+// sent, and then always prepared, signed and broadcast.
+func (m *Manager) TransferERC20(
+	ctx context.Context,
+	from utils.Address,
+	token utils.Token,
+	to utils.Address,
+	amount *big.Int,
+) (string, error) {
+	if to == nil || !common.IsHexAddress(to.String()) {
+		return "", utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid recipient address"))
+	}
+	return m.sendERC20(ctx, from, token, "transfer", common.HexToAddress(to.String()), amount)
+}
+
+// ApproveERC20 allows the spender to transfer up to an amount of the owner's ERC20 tokens.
+func (m *Manager) ApproveERC20(
+	ctx context.Context,
+	owner utils.Address,
+	token utils.Token,
+	spender utils.Address,
+	amount *big.Int,
+) (string, error) {
+	if spender == nil || !common.IsHexAddress(spender.String()) {
+		return "", utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid spender address"))
+	}
+	return m.sendERC20(ctx, owner, token, "approve", common.HexToAddress(spender.String()), amount)
+}
+
+// TransferFromERC20 transfers an amount of an ERC20 token from the owner to the recipient,
+// spending the allowance the owner granted to the spender.
+func (m *Manager) TransferFromERC20(
+	ctx context.Context,
+	spender utils.Address,
+	token utils.Token,
+	owner utils.Address,
+	to utils.Address,
+	amount *big.Int,
+) (string, error) {
+	if owner == nil || !common.IsHexAddress(owner.String()) {
+		return "", utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid owner address"))
+	}
+	if to == nil || !common.IsHexAddress(to.String()) {
+		return "", utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid recipient address"))
+	}
+	return m.sendERC20(ctx, spender, token, "transferFrom",
+		common.HexToAddress(owner.String()), common.HexToAddress(to.String()), amount)
+}
+
+// Allowance returns the amount of an ERC20 token the spender is still allowed to transfer
+// on behalf of the owner.
+func (m *Manager) Allowance(ctx context.Context, token utils.Token, owner, spender utils.Address) (*big.Int, error) {
+	if owner == nil || !common.IsHexAddress(owner.String()) || spender == nil || !common.IsHexAddress(spender.String()) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidAddress)
+	}
+
+	var allowance *big.Int
+	err := m.callToken(ctx, token, utils.ERC20, erc20Abi, "allowance", &allowance,
+		common.HexToAddress(owner.String()), common.HexToAddress(spender.String()))
+	if err != nil {
+		return nil, err
+	}
+	return allowance, nil
+}
+
+// TotalSupply returns the total supply of an ERC20 token.
+func (m *Manager) TotalSupply(ctx context.Context, token utils.Token) (*big.Int, error) {
+	var supply *big.Int
+	if err := m.callToken(ctx, token, utils.ERC20, erc20Abi, "totalSupply", &supply); err != nil {
+		return nil, err
+	}
+	return supply, nil
+}
+
+// GetERC20Token reads the name, symbol and decimals of the ERC20 token at the address.
+// Names and symbols returned as bytes32 by tokens predating the standard are supported.
+func (m *Manager) GetERC20Token(ctx context.Context, address utils.Address) (utils.Token, error) {
+	token := utils.Token{Type: utils.ERC20, Address: &address}
+
+	name, err := m.erc20Text(ctx, token, "name")
+	if err != nil {
+		return utils.Token{}, err
+	}
+	symbol, err := m.erc20Text(ctx, token, "symbol")
+	if err != nil {
+		return utils.Token{}, err
+	}
+
+	var decimals uint8
+	if err := m.callToken(ctx, token, utils.ERC20, erc20Abi, "decimals", &decimals); err != nil {
+		return utils.Token{}, err
+	}
+
+	token.Name = name
+	token.Symbol = symbol
+	token.Decimals = int(decimals)
+	return token, nil
+}
+
+// sendERC20 builds a call to a state-changing ERC20 method, checks that it succeeds and
+// sends it. Tokens that return no value, such as USDT, are treated as successful as long
+// as the token address holds contract code. The transaction is always prepared before
+// signing, whether or not the manager was created with WithAutoPrepare.
+func (m *Manager) sendERC20(
+	ctx context.Context,
+	from utils.Address,
+	token utils.Token,
+	method string,
+	args ...interface{},
+) (string, error) {
+	if m.client == nil {
+		return "", utils.WrapError(utils.ErrClientNotStarted)
+	}
+	if from == nil || !common.IsHexAddress(from.String()) {
+		return "", utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid sender address"))
+	}
+	contract, err := tokenAddress(token, utils.ERC20)
+	if err != nil {
+		return "", err
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(erc20Abi))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ERC20 ABI: %w", err)
+	}
+	data, err := parsedABI.Pack(method, args...)
+	if err != nil {
+		return "", utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	// Simulate the call from the sender before spending gas on it
+	sender := common.HexToAddress(from.String())
+	output, err := m.client.CallContract(ctx, ethereum.CallMsg{From: sender, To: &contract, Data: data}, nil)
+	if err != nil {
+		return "", wrapCallError(utils.ErrEVMFailedToCallContract, err)
+	}
+	if len(output) == 0 {
+		// Calls to accounts without code succeed with no output too
+		code, err := m.client.CodeAt(ctx, contract, nil)
+		if err != nil {
+			return "", utils.WrapError(utils.ErrEVMFailedToCallContract, err)
+		}
+		if len(code) == 0 {
+			return "", utils.WrapError(utils.ErrEVMInvalidToken, fmt.Errorf("no contract code at %s", contract.Hex()))
+		}
+	} else {
+		var ok bool
+		if err := parsedABI.UnpackIntoInterface(&ok, method, output); err != nil {
+			return "", utils.WrapError(utils.ErrEVMFailedToUnpackOutput, err)
+		}
+		if !ok {
+			return "", utils.WrapError(utils.ErrEVMTokenOperationRejected, fmt.Errorf("%s returned false", method))
+		}
+	}
+
+	txType := utils.ContractCall
+	tx := NewTransaction(nil, from, *token.Address, big.NewInt(0), &txType, nil, nil, nil, 0, nil, nil, 0, data)
+	// The transaction is built here without fees, gas limit or nonce, so the caller never
+	// gets a chance to prepare it and it is prepared regardless of WithAutoPrepare
+	return m.sendTransaction(ctx, tx, true)
+}
+
+// erc20Text reads a string metadata method of an ERC20 token, falling back to decoding
+// a bytes32 output.
+func (m *Manager) erc20Text(ctx context.Context, token utils.Token, method string) (string, error) {
+	var text string
+	err := m.callToken(ctx, token, utils.ERC20, erc20Abi, method, &text)
+	if err == nil {
+		return text, nil
+	}
+
+	var raw [32]byte
+	if m.callToken(ctx, token, utils.ERC20, erc20Bytes32Abi, method, &raw) != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(raw[:], "\x00")), nil
+}
@@ -0,0 +1,256 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenContract answers contract calls by method signature, like a deployed token would.
+func tokenContract(t *testing.T, responses map[string][]byte) func(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return func(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+		for signature, output := range responses {
+			if bytes.HasPrefix(msg.Data, crypto.Keccak256([]byte(signature))[:4]) {
+				return output, nil
+			}
+		}
+		t.Logf("unexpected call data %x", msg.Data)
+		return nil, errors.New("execution reverted")
+	}
+}
+
+// abiEncode ABI-encodes a single value of the given type.
+func abiEncode(t *testing.T, typ string, value interface{}) []byte {
+	abiType, err := abi.NewType(typ, "", nil)
+	assert.NoError(t, err)
+	encoded, err := abi.Arguments{{Type: abiType}}.Pack(value)
+	assert.NoError(t, err)
+	return encoded
+}
+
+// expectPrepare sets up the calls PrepareTransaction makes for a transaction without any fields.
+func expectPrepare(mockClient *mock_evm.MockClientInterface) {
+	mockClient.EXPECT().ChainID(gomock.Any()).Return(big.NewInt(1), nil)
+	mockClient.EXPECT().PendingNonceAt(gomock.Any(), gomock.Any()).Return(uint64(7), nil)
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(100), big.NewInt(100)},
+		Reward:       [][]*big.Int{{big.NewInt(1), big.NewInt(2), big.NewInt(3)}},
+		GasUsedRatio: []float64{0.5},
+	}, nil)
+	mockClient.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(50000), nil)
+}
+
+// newERC20Token returns an ERC20 token at a random address.
+func newERC20Token() (utils.Token, common.Address) {
+	address := generateRandomAddress()
+	return utils.Token{Type: utils.ERC20, Address: &address}, common.HexToAddress(address.String())
+}
+
+// TestManager_TransferERC20 tests that a token transfer is checked, prepared and sent to the token contract.
+// go test -v -cover ./pkg/evm -run TestManager_TransferERC20
+func TestManager_TransferERC20(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl)
+	token, contract := newERC20Token()
+	to := generateRandomAddress()
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"transfer(address,uint256)": abiEncode(t, "bool", true),
+		}))
+	expectPrepare(mockClient)
+
+	var sent *types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = tx
+			return nil
+		})
+
+	// Act
+	hash, err := manager.TransferERC20(context.Background(), from, token, to, big.NewInt(500))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sent.Hash().Hex(), hash)
+	assert.Equal(t, contract, *sent.To())
+	assert.Equal(t, 0, sent.Value().Sign())
+	assert.Equal(t, uint64(7), sent.Nonce())
+	assert.Equal(t, crypto.Keccak256([]byte("transfer(address,uint256)"))[:4], sent.Data()[:4])
+	assert.Equal(t, common.HexToAddress(to.String()), common.BytesToAddress(sent.Data()[4:36]))
+	assert.Equal(t, big.NewInt(500), new(big.Int).SetBytes(sent.Data()[36:]))
+}
+
+// TestManager_TransferERC20_NoReturnValue tests that tokens returning nothing, such as USDT, are supported.
+// go test -v -cover ./pkg/evm -run TestManager_TransferERC20_NoReturnValue
+func TestManager_TransferERC20_NoReturnValue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl)
+	token, contract := newERC20Token()
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"approve(address,uint256)": {},
+		}))
+	mockClient.EXPECT().CodeAt(gomock.Any(), contract, nil).Return([]byte{0x60, 0x80}, nil)
+	expectPrepare(mockClient)
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).Return(nil)
+
+	// Act
+	_, err := manager.ApproveERC20(context.Background(), from, token, generateRandomAddress(), big.NewInt(500))
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+// TestManager_TransferERC20_Rejected tests that transfers a token rejects or reverts are never sent.
+// go test -v -cover ./pkg/evm -run TestManager_TransferERC20_Rejected
+func TestManager_TransferERC20_Rejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl)
+	token, _ := newERC20Token()
+
+	// The token returns false
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"transferFrom(address,address,uint256)": abiEncode(t, "bool", false),
+		}))
+
+	_, err := manager.TransferFromERC20(context.Background(), from, token, generateRandomAddress(),
+		generateRandomAddress(), big.NewInt(500))
+	assert.ErrorContains(t, err, utils.ErrEVMTokenOperationRejected)
+
+	// The token reverts
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		Return(nil, errors.New("execution reverted: ERC20: transfer amount exceeds balance"))
+
+	_, err = manager.TransferERC20(context.Background(), from, token, generateRandomAddress(), big.NewInt(500))
+	assert.ErrorContains(t, err, utils.ErrEVMExecutionReverted)
+
+	// Not an ERC20 token
+	_, err = manager.TransferERC20(context.Background(), from, utils.Token{Type: utils.Native}, generateRandomAddress(),
+		big.NewInt(500))
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+}
+
+// TestManager_TransferERC20_NoCode tests that transfers to a token address without contract code are never sent.
+// go test -v -cover ./pkg/evm -run TestManager_TransferERC20_NoCode
+func TestManager_TransferERC20_NoCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl)
+	token, contract := newERC20Token()
+
+	// Calls to an account without code succeed with no output
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(nil, nil).Times(2)
+	mockClient.EXPECT().CodeAt(gomock.Any(), contract, nil).Return(nil, nil)
+
+	// Act
+	_, err := manager.TransferERC20(context.Background(), from, token, generateRandomAddress(), big.NewInt(500))
+
+	// Assert
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+	assert.ErrorContains(t, err, contract.Hex())
+
+	// Failing to fetch the code is reported
+	mockClient.EXPECT().CodeAt(gomock.Any(), contract, nil).Return(nil, errors.New("connection refused"))
+	_, err = manager.TransferERC20(context.Background(), from, token, generateRandomAddress(), big.NewInt(500))
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToCallContract)
+}
+
+// TestManager_AllowanceAndTotalSupply tests reading the allowance and total supply of a token.
+// go test -v -cover ./pkg/evm -run TestManager_AllowanceAndTotalSupply
+func TestManager_AllowanceAndTotalSupply(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, owner := newTestManager(t, ctrl)
+	token, contract := newERC20Token()
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(func(ctx context.Context, msg ethereum.CallMsg, block *big.Int) ([]byte, error) {
+			assert.Equal(t, contract, *msg.To)
+			return tokenContract(t, map[string][]byte{
+				"allowance(address,address)": abiEncode(t, "uint256", big.NewInt(42)),
+				"totalSupply()":              abiEncode(t, "uint256", big.NewInt(1000000)),
+			})(ctx, msg, block)
+		}).Times(2)
+
+	// Act
+	allowance, err := manager.Allowance(context.Background(), token, owner, generateRandomAddress())
+	assert.NoError(t, err)
+	supply, err := manager.TotalSupply(context.Background(), token)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, big.NewInt(42), allowance)
+	assert.Equal(t, big.NewInt(1000000), supply)
+}
+
+// TestManager_GetERC20Token tests reading token metadata, including bytes32 names and symbols.
+// go test -v -cover ./pkg/evm -run TestManager_GetERC20Token
+func TestManager_GetERC20Token(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl)
+
+	// A standard token
+	usdc := generateRandomAddress()
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"name()":     abiEncode(t, "string", "USD Coin"),
+			"symbol()":   abiEncode(t, "string", "USDC"),
+			"decimals()": abiEncode(t, "uint8", uint8(6)),
+		})).Times(3)
+
+	token, err := manager.GetERC20Token(context.Background(), usdc)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.ERC20, token.Type)
+	assert.Equal(t, usdc.String(), (*token.Address).String())
+	assert.Equal(t, "USD Coin", token.Name)
+	assert.Equal(t, "USDC", token.Symbol)
+	assert.Equal(t, 6, token.Decimals)
+
+	// A token returning bytes32, such as MKR
+	var name, symbol [32]byte
+	copy(name[:], "Maker")
+	copy(symbol[:], "MKR")
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"name()":     name[:],
+			"symbol()":   symbol[:],
+			"decimals()": abiEncode(t, "uint8", uint8(18)),
+		})).Times(5)
+
+	token, err = manager.GetERC20Token(context.Background(), generateRandomAddress())
+	assert.NoError(t, err)
+	assert.Equal(t, "Maker", token.Name)
+	assert.Equal(t, "MKR", token.Symbol)
+	assert.Equal(t, 18, token.Decimals)
+
+	// Not a token
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(nil, errors.New("execution reverted")).Times(2)
+
+	_, err = manager.GetERC20Token(context.Background(), generateRandomAddress())
+	assert.ErrorContains(t, err, utils.ErrEVMExecutionReverted)
+}
@@ -0,0 +1,116 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// OwnerOf returns the owner of an ERC721 token.
+func (m *Manager) OwnerOf(ctx context.Context, token utils.Token) (utils.Address, error) {
+	if token.ID == nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidToken, errors.New("missing token ID"))
+	}
+
+	var owner common.Address
+	if err := m.callToken(ctx, token, utils.ERC721, erc721Abi, "ownerOf", &owner, token.ID); err != nil {
+		return nil, err
+	}
+	return &Address{address: owner, network: m.network}, nil
+}
+
+// TokenURI returns the metadata URI of an ERC721 token.
+func (m *Manager) TokenURI(ctx context.Context, token utils.Token) (string, error) {
+	if token.ID == nil {
+		return "", utils.WrapError(utils.ErrEVMInvalidToken, errors.New("missing token ID"))
+	}
+
+	var uri string
+	if err := m.callToken(ctx, token, utils.ERC721, erc721Abi, "tokenURI", &uri, token.ID); err != nil {
+		return "", err
+	}
+	return uri, nil
+}
+
+// TransferERC721 transfers an ERC721 token from the sender to the recipient with
+// safeTransferFrom. The transaction is built here, so it is always prepared before being
+// signed and broadcast, even without WithAutoPrepare. Use NewERC721TransferTransaction and
+// SendTransaction to set the fees or nonce yourself.
+func (m *Manager) TransferERC721(ctx context.Context, from utils.Address, token utils.Token, to utils.Address) (string, error) {
+	tx, err := NewERC721TransferTransaction(from, token, to, nil)
+	if err != nil {
+		return "", err
+	}
+	return m.sendTransaction(ctx, tx, true)
+}
+
+// NewERC721TransferTransaction creates a transaction calling safeTransferFrom on the token
+// contract to move the token from the sender to the recipient. When data is not nil, it is
+// passed on to the recipient's onERC721Received hook. Gas fields, nonce and chain ID are left
+// for PrepareTransaction to fill in.
+func NewERC721TransferTransaction(from utils.Address, token utils.Token, to utils.Address, data []byte) (utils.Transaction, error) {
+	if from == nil || !common.IsHexAddress(from.String()) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid sender address"))
+	}
+	if to == nil || !common.IsHexAddress(to.String()) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidAddress, errors.New("invalid recipient address"))
+	}
+	if _, err := tokenAddress(token, utils.ERC721); err != nil {
+		return nil, err
+	}
+	if token.ID == nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidToken, errors.New("missing token ID"))
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(erc721Abi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC721 ABI: %w", err)
+	}
+
+	sender := common.HexToAddress(from.String())
+	recipient := common.HexToAddress(to.String())
+	var input []byte
+	if data == nil {
+		input, err = parsedABI.Pack("safeTransferFrom", sender, recipient, token.ID)
+	} else {
+		input, err = parsedABI.Pack("safeTransferFrom0", sender, recipient, token.ID, data)
+	}
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	txType := utils.ContractCall
+	return NewTransaction(nil, from, *token.Address, big.NewInt(0), &txType, nil, nil, nil, 0, nil, nil, 0, input), nil
+}
+
+// getERC721Balance returns the number of tokens of the ERC721 contract the address owns or,
+// when the token has an ID, 1 if the address owns that token and 0 otherwise.
+func (m *Manager) getERC721Balance(ctx context.Context, address utils.Address, token utils.Token) (*big.Int, error) {
+	if address == nil || !common.IsHexAddress(address.String()) {
+		return nil, utils.WrapError(utils.ErrEVMInvalidAddress)
+	}
+
+	if token.ID != nil {
+		owner, err := m.OwnerOf(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if common.HexToAddress(owner.String()) == common.HexToAddress(address.String()) {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	}
+
+	var balance *big.Int
+	err := m.callToken(ctx, token, utils.ERC721, erc721Abi, "balanceOf", &balance, common.HexToAddress(address.String()))
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
@@ -0,0 +1,174 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newERC721Token returns an ERC721 token with the given ID at a random address.
+func newERC721Token(id int64) utils.Token {
+	address := generateRandomAddress()
+	return utils.Token{Type: utils.ERC721, Address: &address, ID: big.NewInt(id)}
+}
+
+// safeTransferFromArguments returns the arguments of safeTransferFrom with data.
+func safeTransferFromArguments(t *testing.T) abi.Arguments {
+	var arguments abi.Arguments
+	for _, typ := range []string{"address", "address", "uint256", "bytes"} {
+		abiType, err := abi.NewType(typ, "", nil)
+		assert.NoError(t, err)
+		arguments = append(arguments, abi.Argument{Type: abiType})
+	}
+	return arguments
+}
+
+// TestManager_GetBalance_ERC721 tests the GetBalance method for a collection and for a single NFT.
+// go test -v -cover ./pkg/evm -run TestManager_GetBalance_ERC721
+func TestManager_GetBalance_ERC721(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, owner := newTestManager(t, ctrl)
+	token := newERC721Token(7)
+	collection := utils.Token{Type: utils.ERC721, Address: token.Address}
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"balanceOf(address)": abiEncode(t, "uint256", big.NewInt(3)),
+			"ownerOf(uint256)":   abiEncode(t, "address", common.HexToAddress(owner.String())),
+		})).Times(3)
+
+	// Number of tokens of the collection held
+	balance, err := manager.GetBalance(context.Background(), owner, collection)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(3), balance)
+
+	// A single token held by the address
+	balance, err = manager.GetBalance(context.Background(), owner, token)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1), balance)
+
+	// A single token held by someone else
+	balance, err = manager.GetBalance(context.Background(), generateRandomAddress(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(0), balance)
+}
+
+// TestManager_OwnerOfAndTokenURI tests reading the owner and metadata URI of an NFT.
+// go test -v -cover ./pkg/evm -run TestManager_OwnerOfAndTokenURI
+func TestManager_OwnerOfAndTokenURI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, owner := newTestManager(t, ctrl)
+	token := newERC721Token(42)
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"ownerOf(uint256)":  abiEncode(t, "address", common.HexToAddress(owner.String())),
+			"tokenURI(uint256)": abiEncode(t, "string", "ipfs://bafy/42.json"),
+		})).Times(2)
+
+	// Act
+	gotOwner, err := manager.OwnerOf(context.Background(), token)
+	assert.NoError(t, err)
+	uri, err := manager.TokenURI(context.Background(), token)
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, owner.String(), gotOwner.String())
+	assert.Equal(t, string(utils.Ethereum), gotOwner.Network())
+	assert.Equal(t, "ipfs://bafy/42.json", uri)
+
+	// Nonexistent tokens revert
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		Return(nil, errors.New("execution reverted: ERC721: invalid token ID"))
+
+	_, err = manager.OwnerOf(context.Background(), token)
+	assert.ErrorContains(t, err, "ERC721: invalid token ID")
+
+	// A token ID is required
+	_, err = manager.TokenURI(context.Background(), utils.Token{Type: utils.ERC721, Address: token.Address})
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+}
+
+// TestNewERC721TransferTransaction tests building safeTransferFrom transactions.
+// go test -v -cover ./pkg/evm -run TestNewERC721TransferTransaction
+func TestNewERC721TransferTransaction(t *testing.T) {
+	from := generateRandomAddress()
+	to := generateRandomAddress()
+	token := newERC721Token(9)
+
+	// Without data
+	tx, err := evm.NewERC721TransferTransaction(from, token, to, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, (*token.Address).String(), tx.To().String())
+	assert.Equal(t, 0, tx.Amount().Sign())
+
+	data := tx.Payload()["data"].([]byte)
+	assert.Equal(t, crypto.Keccak256([]byte("safeTransferFrom(address,address,uint256)"))[:4], data[:4])
+	assert.Len(t, data, 4+3*32)
+
+	// With data for the recipient
+	tx, err = evm.NewERC721TransferTransaction(from, token, to, []byte{0x01, 0x02})
+	assert.NoError(t, err)
+
+	data = tx.Payload()["data"].([]byte)
+	assert.Equal(t, crypto.Keccak256([]byte("safeTransferFrom(address,address,uint256,bytes)"))[:4], data[:4])
+	args, err := safeTransferFromArguments(t).Unpack(data[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToAddress(from.String()), args[0])
+	assert.Equal(t, common.HexToAddress(to.String()), args[1])
+	assert.Equal(t, big.NewInt(9), args[2])
+	assert.Equal(t, []byte{0x01, 0x02}, args[3])
+
+	// Invalid tokens
+	_, err = evm.NewERC721TransferTransaction(from, utils.Token{Type: utils.ERC721, Address: token.Address}, to, nil)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+	erc20, _ := newERC20Token()
+	erc20.ID = big.NewInt(9)
+	_, err = evm.NewERC721TransferTransaction(from, erc20, to, nil)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+}
+
+// TestManager_TransferERC721 tests that an NFT transfer is prepared and sent to the token contract.
+// go test -v -cover ./pkg/evm -run TestManager_TransferERC721
+func TestManager_TransferERC721(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl)
+	token := newERC721Token(1)
+	to := generateRandomAddress()
+
+	expectPrepare(mockClient)
+	var sent *types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = tx
+			return nil
+		})
+
+	// Act
+	hash, err := manager.TransferERC721(context.Background(), from, token, to)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sent.Hash().Hex(), hash)
+	assert.Equal(t, (*token.Address).String(), sent.To().Hex())
+	assert.Equal(t, crypto.Keccak256([]byte("safeTransferFrom(address,address,uint256)"))[:4], sent.Data()[:4])
+	assert.Equal(t, common.HexToAddress(from.String()), common.BytesToAddress(sent.Data()[4:36]))
+	assert.Equal(t, common.HexToAddress(to.String()), common.BytesToAddress(sent.Data()[36:68]))
+	assert.Equal(t, big.NewInt(1), new(big.Int).SetBytes(sent.Data()[68:]))
+}
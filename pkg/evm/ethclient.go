@@ -60,7 +60,29 @@ type ClientInterface interface {
 	// Filters and Subscriptions
 	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
 	SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
 
 	// Miscellaneous
+	Client() *rpc.Client
 	Close()
 }
+
+// RPCCaller sends raw JSON-RPC requests, as *rpc.Client does. Client wrappers implement it
+// so that requests made outside the ClientInterface methods, such as batches and methods
+// ethclient lacks, still go through their retries, budgets and failover.
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// rpcCaller returns what raw requests to the client are sent through, nil when it has no
+// RPC connection.
+func rpcCaller(client ClientInterface) RPCCaller {
+	if caller, ok := client.(RPCCaller); ok {
+		return caller
+	}
+	if rpcClient := client.Client(); rpcClient != nil {
+		return rpcClient
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package evm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// EventRegistry maps event signatures to their ABI definitions so that logs can be decoded
+// into named events. It ships with the ERC20, ERC721 and ERC1155 transfer and approval
+// events, and is safe for concurrent use.
+type EventRegistry struct {
+	mu        sync.RWMutex
+	events    map[common.Hash][]abi.Event                     // Candidates by topic0, most recently registered first
+	standards map[utils.TokenType]map[common.Hash][]abi.Event // Built-in events of each token standard
+	contracts map[common.Address]utils.TokenType              // Token standard of registered contracts
+}
+
+// NewEventRegistry creates a new EventRegistry instance with the built-in token events.
+func NewEventRegistry() *EventRegistry {
+	r := &EventRegistry{
+		events:    make(map[common.Hash][]abi.Event),
+		standards: make(map[utils.TokenType]map[common.Hash][]abi.Event),
+		contracts: make(map[common.Address]utils.TokenType),
+	}
+	builtins := []struct {
+		standard utils.TokenType
+		abiJSON  string
+	}{
+		{utils.ERC20, erc20EventsAbi},
+		{utils.ERC721, erc721EventsAbi},
+		{utils.ERC1155, erc1155EventsAbi},
+	}
+	for _, builtin := range builtins {
+		parsed, err := abi.JSON(strings.NewReader(builtin.abiJSON))
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in event ABI: %v", err))
+		}
+		events := make(map[common.Hash][]abi.Event)
+		for _, event := range parsed.Events {
+			events[event.ID] = append(events[event.ID], event)
+			r.Register(event)
+		}
+		r.standards[builtin.standard] = events
+	}
+	return r
+}
+
+// RegisterContract registers the token standard a contract implements. Logs it emits are
+// decoded with the events of that standard first, which tells apart events sharing a
+// signature across standards, such as the ERC721 and ERC1155 ApprovalForAll events.
+func (r *EventRegistry) RegisterContract(address common.Address, standard utils.TokenType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.standards[standard]; !ok {
+		return utils.WrapError(fmt.Sprintf("%s: %v", utils.ErrUnsupportedTokenType, standard))
+	}
+	r.contracts[address] = standard
+	return nil
+}
+
+// RegisterABI registers every event of a contract ABI given as JSON.
+func (r *EventRegistry) RegisterABI(abiJSON string) error {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return err
+	}
+	for _, event := range parsed.Events {
+		r.Register(event)
+	}
+	return nil
+}
+
+// Register registers an event definition. Events sharing a signature are told apart by their
+// number of indexed inputs, as with the ERC20 and ERC721 Transfer events. A definition with
+// the same signature and indexed inputs as a registered one takes precedence over it.
+func (r *EventRegistry) Register(event abi.Event) {
+	if event.Anonymous {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidates := []abi.Event{event}
+	for _, existing := range r.events[event.ID] {
+		if indexedCount(existing) != indexedCount(event) {
+			candidates = append(candidates, existing)
+		}
+	}
+	r.events[event.ID] = candidates
+}
+
+// Decode decodes a log into a named event with its indexed and non-indexed parameters. It
+// reports false when no registered event matches the log. Logs of contracts registered with
+// RegisterContract are decoded by their standard; other logs by the registered definitions.
+func (r *EventRegistry) Decode(log types.Log) (utils.Event, bool, error) {
+	if len(log.Topics) == 0 {
+		return utils.Event{}, false, nil
+	}
+
+	event, ok := r.lookup(log.Address, log.Topics[0], len(log.Topics)-1)
+	if !ok {
+		return utils.Event{}, false, nil
+	}
+
+	params := make(map[string]interface{})
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(params, indexed, log.Topics[1:]); err != nil {
+		return utils.Event{}, true, fmt.Errorf("failed to decode %s topics: %w", event.Name, err)
+	}
+	if err := event.Inputs.NonIndexed().UnpackIntoMap(params, log.Data); err != nil {
+		return utils.Event{}, true, fmt.Errorf("failed to decode %s data: %w", event.Name, err)
+	}
+
+	return utils.Event{Name: event.Name, Params: params}, true, nil
+}
+
+// lookup returns the event registered for the signature with the given number of indexed
+// inputs, preferring the events of the standard the emitting contract is registered with.
+func (r *EventRegistry) lookup(address common.Address, id common.Hash, indexed int) (abi.Event, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if standard, ok := r.contracts[address]; ok {
+		if event, ok := findEvent(r.standards[standard][id], indexed); ok {
+			return event, true
+		}
+	}
+	return findEvent(r.events[id], indexed)
+}
+
+// findEvent returns the first of the candidates with the given number of indexed inputs.
+func findEvent(candidates []abi.Event, indexed int) (abi.Event, bool) {
+	for _, event := range candidates {
+		if indexedCount(event) == indexed {
+			return event, true
+		}
+	}
+	return abi.Event{}, false
+}
+
+// indexedCount returns the number of indexed inputs of an event.
+func indexedCount(event abi.Event) int {
+	count := 0
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			count++
+		}
+	}
+	return count
+}
+
+// EventRegistry returns the registry the Manager decodes transaction events with.
+func (m *Manager) EventRegistry() *EventRegistry {
+	return m.eventRegistry
+}
+
+// decodeEvents decodes the logs of a receipt into events, skipping logs that no registered
+// event matches or that do not follow the registered definition. It also returns the
+// parameters of every log keyed by event name, or by topic0 with no parameters for the logs
+// it could not decode. Repeated keys are suffixed from their second occurrence on, as in
+// "Transfer#2".
+func (m *Manager) decodeEvents(logs []*types.Log) ([]utils.Event, map[string]interface{}) {
+	var events []utils.Event
+	byName := make(map[string]interface{})
+	occurrences := make(map[string]int)
+	add := func(key string, params map[string]interface{}) {
+		occurrences[key]++
+		if n := occurrences[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		byName[key] = params
+	}
+
+	for _, log := range logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+		event, ok, err := m.eventRegistry.Decode(*log)
+		if !ok || err != nil {
+			add(log.Topics[0].Hex(), make(map[string]interface{}))
+			continue
+		}
+
+		event.Addr = &Address{address: log.Address, network: m.network}
+		events = append(events, event)
+		add(event.Name, event.Params)
+	}
+	return events, byName
+}
@@ -0,0 +1,235 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	transferTopic      = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	transferBatchTopic = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+	testEventFromAddr  = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	testEventToAddr    = common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	testEventTokenAddr = common.HexToAddress("0x00000000000000000000000000000000000000c3")
+	testEventOperator  = common.HexToAddress("0x00000000000000000000000000000000000000d4")
+)
+
+// packArgs ABI-encodes values of the given types.
+func packArgs(t *testing.T, typeNames []string, values ...interface{}) []byte {
+	var args abi.Arguments
+	for _, typeName := range typeNames {
+		argType, err := abi.NewType(typeName, "", nil)
+		assert.NoError(t, err)
+		args = append(args, abi.Argument{Type: argType})
+	}
+	data, err := args.Pack(values...)
+	assert.NoError(t, err)
+	return data
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestEventRegistry_Decode_Transfers
+func TestEventRegistry_Decode_Transfers(t *testing.T) {
+	registry := evm.NewEventRegistry()
+
+	// ERC20 Transfer: the value is in the data
+	event, ok, err := registry.Decode(types.Log{
+		Address: testEventTokenAddr,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(testEventFromAddr.Bytes()), common.BytesToHash(testEventToAddr.Bytes())},
+		Data:    packArgs(t, []string{"uint256"}, big.NewInt(500)),
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Transfer", event.Name)
+	assert.Equal(t, testEventFromAddr, event.Params["from"])
+	assert.Equal(t, testEventToAddr, event.Params["to"])
+	assert.Equal(t, big.NewInt(500), event.Params["value"])
+
+	// ERC721 Transfer: same signature, but the token ID is indexed
+	event, ok, err = registry.Decode(types.Log{
+		Address: testEventTokenAddr,
+		Topics: []common.Hash{transferTopic, common.BytesToHash(testEventFromAddr.Bytes()),
+			common.BytesToHash(testEventToAddr.Bytes()), common.BigToHash(big.NewInt(42))},
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Transfer", event.Name)
+	assert.Equal(t, big.NewInt(42), event.Params["tokenId"])
+	assert.NotContains(t, event.Params, "value")
+
+	// ERC1155 TransferBatch: arrays in the data
+	event, ok, err = registry.Decode(types.Log{
+		Address: testEventTokenAddr,
+		Topics: []common.Hash{transferBatchTopic, common.BytesToHash(testEventOperator.Bytes()),
+			common.BytesToHash(testEventFromAddr.Bytes()), common.BytesToHash(testEventToAddr.Bytes())},
+		Data: packArgs(t, []string{"uint256[]", "uint256[]"},
+			[]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(10), big.NewInt(20)}),
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "TransferBatch", event.Name)
+	assert.Equal(t, testEventOperator, event.Params["operator"])
+	assert.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, event.Params["ids"])
+	assert.Equal(t, []*big.Int{big.NewInt(10), big.NewInt(20)}, event.Params["values"])
+
+	// Unknown signature, or a known signature with an unknown number of indexed inputs
+	_, ok, err = registry.Decode(types.Log{Topics: []common.Hash{common.HexToHash("0x1234")}})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = registry.Decode(types.Log{Topics: []common.Hash{transferTopic}})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Malformed data for a known event
+	_, ok, err = registry.Decode(types.Log{
+		Topics: []common.Hash{transferTopic, common.BytesToHash(testEventFromAddr.Bytes()), common.BytesToHash(testEventToAddr.Bytes())},
+		Data:   []byte{0x01},
+	})
+	assert.True(t, ok)
+	assert.Error(t, err)
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestEventRegistry_RegisterABI
+func TestEventRegistry_RegisterABI(t *testing.T) {
+	registry := evm.NewEventRegistry()
+
+	err := registry.RegisterABI(`[{"anonymous":false,"inputs":[
+		{"indexed":true,"name":"sender","type":"address"},
+		{"indexed":false,"name":"amountIn","type":"uint256"},
+		{"indexed":false,"name":"amountOut","type":"uint256"}
+	],"name":"Swap","type":"event"}]`)
+	assert.NoError(t, err)
+
+	event, ok, err := registry.Decode(types.Log{
+		Topics: []common.Hash{crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256)")), common.BytesToHash(testEventFromAddr.Bytes())},
+		Data:   packArgs(t, []string{"uint256", "uint256"}, big.NewInt(3), big.NewInt(7)),
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Swap", event.Name)
+	assert.Equal(t, testEventFromAddr, event.Params["sender"])
+	assert.Equal(t, big.NewInt(3), event.Params["amountIn"])
+	assert.Equal(t, big.NewInt(7), event.Params["amountOut"])
+
+	assert.Error(t, registry.RegisterABI("not an abi"))
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestEventRegistry_RegisterContract
+func TestEventRegistry_RegisterContract(t *testing.T) {
+	registry := evm.NewEventRegistry()
+
+	erc721Contract := common.HexToAddress("0x00000000000000000000000000000000000000e5")
+	erc1155Contract := common.HexToAddress("0x00000000000000000000000000000000000000f6")
+	assert.NoError(t, registry.RegisterContract(erc721Contract, utils.ERC721))
+	assert.NoError(t, registry.RegisterContract(erc1155Contract, utils.ERC1155))
+	assert.ErrorContains(t, registry.RegisterContract(testEventTokenAddr, utils.Native), utils.ErrUnsupportedTokenType)
+
+	// ERC721 and ERC1155 ApprovalForAll share a signature and indexed inputs
+	approvalForAll := func(contract common.Address) types.Log {
+		return types.Log{
+			Address: contract,
+			Topics: []common.Hash{crypto.Keccak256Hash([]byte("ApprovalForAll(address,address,bool)")),
+				common.BytesToHash(testEventFromAddr.Bytes()), common.BytesToHash(testEventOperator.Bytes())},
+			Data: packArgs(t, []string{"bool"}, true),
+		}
+	}
+
+	event, ok, err := registry.Decode(approvalForAll(erc721Contract))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "ApprovalForAll", event.Name)
+	assert.Equal(t, testEventFromAddr, event.Params["owner"])
+	assert.Equal(t, testEventOperator, event.Params["operator"])
+	assert.Equal(t, true, event.Params["approved"])
+
+	event, ok, err = registry.Decode(approvalForAll(erc1155Contract))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, testEventFromAddr, event.Params["account"])
+	assert.NotContains(t, event.Params, "owner")
+
+	// Events outside the standard of a contract still decode by their registered definition
+	event, ok, err = registry.Decode(types.Log{
+		Address: erc1155Contract,
+		Topics:  []common.Hash{transferTopic, common.BytesToHash(testEventFromAddr.Bytes()), common.BytesToHash(testEventToAddr.Bytes())},
+		Data:    packArgs(t, []string{"uint256"}, big.NewInt(500)),
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Transfer", event.Name)
+	assert.Equal(t, big.NewInt(500), event.Params["value"])
+}
+
+// TestManager_GetTransactionDetails_Events tests that known events are decoded and unknown logs are skipped.
+// go test -v -cover ./pkg/evm -run TestManager_GetTransactionDetails_Events
+func TestManager_GetTransactionDetails_Events(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", nil, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	tx, _ := generateSignedTransaction(t, testEventTokenAddr, big.NewInt(1))
+	receipt := &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		BlockNumber: big.NewInt(5),
+		GasUsed:     50000,
+		Logs: []*types.Log{
+			{
+				Address: testEventTokenAddr,
+				Topics:  []common.Hash{common.HexToHash("0x1234")},
+				Index:   0,
+			},
+			{
+				Address: testEventTokenAddr,
+				Topics:  []common.Hash{transferTopic, common.BytesToHash(testEventFromAddr.Bytes()), common.BytesToHash(testEventToAddr.Bytes())},
+				Data:    packArgs(t, []string{"uint256"}, big.NewInt(500)),
+				Index:   1,
+			},
+			{
+				Address: testEventTokenAddr,
+				Topics:  []common.Hash{transferTopic, common.BytesToHash(testEventToAddr.Bytes()), common.BytesToHash(testEventFromAddr.Bytes())},
+				Data:    packArgs(t, []string{"uint256"}, big.NewInt(20)),
+				Index:   2,
+			},
+		},
+	}
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(receipt, nil)
+
+	// Act
+	details, err := manager.GetTransactionDetails(context.Background(), tx.Hash().Hex())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, details.Logs, 3)
+	assert.Len(t, details.DecodedEvents, 2)
+	assert.Equal(t, "Transfer", details.DecodedEvents[0].Name)
+	assert.Equal(t, testEventTokenAddr.Hex(), details.DecodedEvents[0].Addr.String())
+	assert.Equal(t, big.NewInt(500), details.DecodedEvents[0].Params["value"])
+	assert.Equal(t, big.NewInt(20), details.DecodedEvents[1].Params["value"])
+
+	// Events keeps undecoded logs by topic0, and repeated events apart
+	assert.Equal(t, map[string]interface{}{
+		common.HexToHash("0x1234").Hex(): map[string]interface{}{},
+		"Transfer":                       details.DecodedEvents[0].Params,
+		"Transfer#2":                     details.DecodedEvents[1].Params,
+	}, details.Events)
+}
@@ -0,0 +1,260 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// SubscribeEvents streams the logs matching the filter until ctx is done or the subscription
+// is unsubscribed, at which point the log channel is closed.
+//
+// Logs are followed through a log subscription when the transport supports it, and by polling
+// FilterLogs otherwise. After a disconnect the stream resumes from the last delivered log, so
+// logs are neither lost nor delivered twice. Missed blocks are queried in chunks like GetLogs.
+// Logs the node reports as reverted by a reorg are passed on with Removed set; polling does
+// not detect reorgs.
+//
+// When the node keeps failing, the stream ends and the error is delivered on the
+// subscription's Err channel.
+func (m *Manager) SubscribeEvents(ctx context.Context, filter utils.EventFilter) (<-chan utils.Log, utils.Subscription, error) {
+	if m.client == nil {
+		return nil, nil, utils.WrapError(utils.ErrClientNotStarted)
+	}
+
+	query, err := toFilterQuery(filter)
+	if err != nil {
+		return nil, nil, utils.WrapError(utils.ErrEVMFailedToSubscribe, err)
+	}
+
+	stream := &eventStream{
+		manager: m,
+		query:   query,
+		logs:    make(chan utils.Log, 64),
+	}
+	if filter.FromBlock != nil {
+		stream.next = *filter.FromBlock
+	} else {
+		head, err := m.client.BlockNumber(ctx)
+		if err != nil {
+			return nil, nil, utils.WrapError(utils.ErrEVMFailedToSubscribe, err)
+		}
+		stream.next = head + 1
+	}
+
+	sub := event.NewSubscription(func(quit <-chan struct{}) error {
+		defer close(stream.logs)
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-quit:
+				cancel()
+			case <-streamCtx.Done():
+			}
+		}()
+
+		return stream.run(streamCtx)
+	})
+
+	return stream.logs, sub, nil
+}
+
+// eventStreamMaxFailures is the number of consecutive failures after which a SubscribeEvents
+// stream gives up.
+const eventStreamMaxFailures = 5
+
+// eventStream holds the state of a SubscribeEvents stream.
+type eventStream struct {
+	manager *Manager
+	query   ethereum.FilterQuery
+	logs    chan utils.Log
+
+	next        uint64      // Next block to fetch when backfilling
+	cursor      logPosition // Position of the first log not delivered yet
+	unsupported bool        // Whether the transport does not support log subscriptions
+	failures    int         // Consecutive failures to reach the node
+}
+
+// logPosition is the position of a log in the chain.
+type logPosition struct {
+	block uint64
+	index uint
+}
+
+// run streams logs until ctx is done, switching between subscribing and polling. It returns
+// the last error once the node failed too many times in a row.
+func (s *eventStream) run(ctx context.Context) error {
+	ticker := time.NewTicker(s.manager.pollInterval())
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		var err error
+		if !s.unsupported {
+			err = s.subscribe(ctx)
+		} else {
+			err = s.backfill(ctx)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			s.failures = 0
+		} else if s.failures++; s.failures >= eventStreamMaxFailures {
+			return utils.WrapError(utils.ErrEVMFailedToSubscribe, err)
+		}
+
+		// Wait before polling again, or before resubscribing after a disconnect
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// subscribe follows a log subscription until it fails or ctx is done. Logs already in the
+// chain since the last delivered log are backfilled once the subscription is established.
+// A dropped subscription is not an error, since it is established again on the next round.
+func (s *eventStream) subscribe(ctx context.Context) error {
+	ch := make(chan types.Log, 64)
+	sub, err := s.manager.client.SubscribeFilterLogs(ctx, s.query, ch)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			s.unsupported = true
+		}
+		// Poll once so that logs keep flowing while the subscription cannot be established
+		return s.backfill(ctx)
+	}
+	defer sub.Unsubscribe()
+
+	// Logs received while backfilling are deduplicated against the cursor
+	if err := s.backfill(ctx); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Err():
+			return nil
+		case log := <-ch:
+			if !s.deliver(ctx, log) {
+				return nil
+			}
+		}
+	}
+}
+
+// backfill delivers the logs from the next block up to the current head.
+func (s *eventStream) backfill(ctx context.Context) error {
+	head, err := s.manager.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if head < s.next {
+		return nil
+	}
+
+	logs, err := s.manager.filterLogs(ctx, s.query, s.next, head)
+	if err != nil {
+		return err
+	}
+
+	for _, log := range logs {
+		if !s.deliver(ctx, log) {
+			return nil
+		}
+	}
+	s.next = head + 1
+	return nil
+}
+
+// deliver sends a log to the stream unless it was already delivered, and advances the cursor.
+// It reports whether the stream should go on.
+func (s *eventStream) deliver(ctx context.Context, log types.Log) bool {
+	delivered := !s.cursor.accepts(log.BlockNumber, log.Index)
+	switch {
+	case log.Removed && !delivered:
+		return true
+	case log.Removed:
+		// The logs of the reorged block are delivered again from the new chain
+		s.cursor = logPosition{block: log.BlockNumber}
+		s.next = min(s.next, log.BlockNumber)
+	case delivered:
+		return true
+	default:
+		s.cursor = logPosition{block: log.BlockNumber, index: log.Index + 1}
+		s.next = max(s.next, log.BlockNumber)
+	}
+
+	converted, err := convertLog(log, s.manager.network)
+	if err != nil {
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case s.logs <- converted:
+		return true
+	}
+}
+
+// accepts reports whether a log at the given position was not delivered yet.
+func (p logPosition) accepts(block uint64, index uint) bool {
+	return block > p.block || (block == p.block && index >= p.index)
+}
+
+// toFilterQuery converts an event filter to a go-ethereum filter query.
+func toFilterQuery(filter utils.EventFilter) (ethereum.FilterQuery, error) {
+	var query ethereum.FilterQuery
+	for _, address := range filter.Addresses {
+		if address == nil || !common.IsHexAddress(address.String()) {
+			return query, errors.New("invalid filter address")
+		}
+		query.Addresses = append(query.Addresses, common.HexToAddress(address.String()))
+	}
+
+	for _, position := range filter.Topics {
+		var topics []common.Hash
+		for _, topic := range position {
+			topics = append(topics, common.HexToHash(topic))
+		}
+		query.Topics = append(query.Topics, topics)
+	}
+
+	return query, nil
+}
+
+// convertLog converts a go-ethereum log to a utils.Log.
+func convertLog(log types.Log, network utils.Blockchain) (utils.Log, error) {
+	addr, err := NewAddress(log.Address.Hex(), network)
+	if err != nil {
+		return utils.Log{}, utils.WrapError(utils.ErrEVMInvalidAddress, err)
+	}
+	hash, err := NewTxHash(log.TxHash.Hex(), string(network))
+	if err != nil {
+		return utils.Log{}, utils.WrapError(utils.ErrEVMInvalidHash, err)
+	}
+
+	return utils.Log{
+		Addr:        addr,
+		Topics:      topicsToStrings(log.Topics),
+		Data:        log.Data,
+		BlockNumber: log.BlockNumber,
+		TxHash:      hash,
+		Index:       log.Index,
+		Removed:     log.Removed,
+	}, nil
+}
@@ -0,0 +1,240 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/mock/gomock"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var testEventContract = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+// newTestLog returns a log of the test contract at the given position.
+func newTestLog(block uint64, index uint) types.Log {
+	return types.Log{
+		Address:     testEventContract,
+		Topics:      []common.Hash{common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")},
+		BlockNumber: block,
+		TxHash:      common.BigToHash(new(big.Int).SetUint64(block*1000 + uint64(index))),
+		Index:       index,
+	}
+}
+
+// filterRangeMatcher matches filter queries for the test contract over a block range.
+type filterRangeMatcher struct {
+	from, to int64
+}
+
+// filterRange matches filter queries over the given block range.
+func filterRange(from, to int64) gomock.Matcher {
+	return filterRangeMatcher{from: from, to: to}
+}
+
+func (m filterRangeMatcher) Matches(x interface{}) bool {
+	query, ok := x.(ethereum.FilterQuery)
+	return ok && query.FromBlock != nil && query.ToBlock != nil &&
+		query.FromBlock.Cmp(big.NewInt(m.from)) == 0 && query.ToBlock.Cmp(big.NewInt(m.to)) == 0 &&
+		len(query.Addresses) == 1 && query.Addresses[0] == testEventContract
+}
+
+func (m filterRangeMatcher) String() string {
+	return fmt.Sprintf("filters blocks %d to %d", m.from, m.to)
+}
+
+// readLog reads the next log from the stream.
+func readLog(t *testing.T, logs <-chan utils.Log) utils.Log {
+	select {
+	case log, ok := <-logs:
+		assert.True(t, ok, "log channel closed")
+		return log
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for log")
+		return utils.Log{}
+	}
+}
+
+// TestManager_SubscribeEvents_Polling tests that logs are polled with FilterLogs when subscriptions are not supported.
+// go test -v -cover ./pkg/evm -run TestManager_SubscribeEvents_Polling
+func TestManager_SubscribeEvents_Polling(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond))
+	contract, err := evm.NewAddress(testEventContract.Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().SubscribeFilterLogs(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, rpc.ErrNotificationsUnsupported).Times(1)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(12), nil).Times(2)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(14), nil).AnyTimes()
+	mockClient.EXPECT().FilterLogs(gomock.Any(), filterRange(10, 12)).Return([]types.Log{newTestLog(10, 0), newTestLog(12, 1)}, nil)
+	mockClient.EXPECT().FilterLogs(gomock.Any(), filterRange(13, 14)).Return([]types.Log{newTestLog(14, 0)}, nil)
+
+	// Act
+	fromBlock := uint64(10)
+	logs, sub, err := manager.SubscribeEvents(context.Background(), utils.EventFilter{
+		Addresses: []utils.Address{contract},
+		FromBlock: &fromBlock,
+	})
+	assert.NoError(t, err)
+
+	// Assert
+	first := readLog(t, logs)
+	assert.Equal(t, uint64(10), first.BlockNumber)
+	assert.Equal(t, contract.String(), first.Addr.String())
+	assert.Equal(t, uint64(12), readLog(t, logs).BlockNumber)
+	assert.Equal(t, uint64(14), readLog(t, logs).BlockNumber)
+
+	sub.Unsubscribe()
+	for range logs {
+	}
+}
+
+// TestManager_SubscribeEvents_Resume tests that the stream resumes after a disconnect without losing or duplicating logs.
+// go test -v -cover ./pkg/evm -run TestManager_SubscribeEvents_Resume
+func TestManager_SubscribeEvents_Resume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond))
+	contract, err := evm.NewAddress(testEventContract.Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	// Every subscription hands its log channel and a way to fail it to the test
+	type subscription struct {
+		ch   chan<- types.Log
+		fail chan error
+	}
+	subscriptions := make(chan subscription, 2)
+	mockClient.EXPECT().SubscribeFilterLogs(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+			fail := make(chan error, 1)
+			subscriptions <- subscription{ch: ch, fail: fail}
+			return event.NewSubscription(func(quit <-chan struct{}) error {
+				select {
+				case <-quit:
+					return nil
+				case err := <-fail:
+					return err
+				}
+			}), nil
+		}).Times(2)
+
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(100), nil).Times(2)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(102), nil).AnyTimes()
+
+	// The backfill after reconnecting overlaps with the logs already delivered
+	mockClient.EXPECT().FilterLogs(gomock.Any(), filterRange(101, 102)).
+		Return([]types.Log{newTestLog(101, 0), newTestLog(101, 1), newTestLog(102, 0)}, nil)
+
+	// Act
+	logs, sub, err := manager.SubscribeEvents(context.Background(), utils.EventFilter{
+		Addresses: []utils.Address{contract},
+	})
+	assert.NoError(t, err)
+
+	first := <-subscriptions
+	first.ch <- newTestLog(101, 0)
+	first.ch <- newTestLog(101, 1)
+	assert.Equal(t, uint(0), readLog(t, logs).Index)
+	assert.Equal(t, uint(1), readLog(t, logs).Index)
+	first.fail <- errors.New("connection lost")
+
+	// Assert
+	assert.Equal(t, uint64(102), readLog(t, logs).BlockNumber)
+
+	second := <-subscriptions
+	second.ch <- newTestLog(102, 0)
+	second.ch <- newTestLog(103, 0)
+	assert.Equal(t, uint64(103), readLog(t, logs).BlockNumber)
+
+	// A reorg removes the log of block 103 and includes another one
+	removed := newTestLog(103, 0)
+	removed.Removed = true
+	second.ch <- removed
+	replacement := newTestLog(103, 0)
+	replacement.TxHash = common.HexToHash("0xabc")
+	second.ch <- replacement
+
+	reverted := readLog(t, logs)
+	assert.True(t, reverted.Removed)
+	assert.Equal(t, uint64(103), reverted.BlockNumber)
+	added := readLog(t, logs)
+	assert.False(t, added.Removed)
+	assert.Equal(t, common.HexToHash("0xabc").Hex(), added.TxHash.String())
+
+	sub.Unsubscribe()
+	for range logs {
+	}
+}
+
+// TestManager_SubscribeEvents_Errors tests that invalid filters and node failures are reported when subscribing.
+// go test -v -cover ./pkg/evm -run TestManager_SubscribeEvents_Errors
+func TestManager_SubscribeEvents_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithPollInterval(time.Millisecond))
+
+	_, _, err := manager.SubscribeEvents(context.Background(), utils.EventFilter{Addresses: []utils.Address{nil}})
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToSubscribe)
+
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(0), errors.New("connection refused"))
+	_, _, err = manager.SubscribeEvents(context.Background(), utils.EventFilter{})
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToSubscribe)
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+// TestManager_SubscribeEvents_Failures tests that missed blocks are backfilled in chunks and that persistent node failures end the stream.
+// go test -v -cover ./pkg/evm -run TestManager_SubscribeEvents_Failures
+func TestManager_SubscribeEvents_Failures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl,
+		evm.WithPollInterval(time.Millisecond), evm.WithLogQueryConfig(evm.LogQueryConfig{ChunkSize: 2}))
+
+	contract, err := evm.NewAddress(testEventContract.Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().SubscribeFilterLogs(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, rpc.ErrNotificationsUnsupported)
+	gomock.InOrder(
+		mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(13), nil),
+		mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(0), errors.New("connection refused")).AnyTimes(),
+	)
+	mockClient.EXPECT().FilterLogs(gomock.Any(), filterRange(10, 11)).Return([]types.Log{newTestLog(11, 0)}, nil)
+	mockClient.EXPECT().FilterLogs(gomock.Any(), filterRange(12, 13)).Return([]types.Log{newTestLog(13, 0)}, nil)
+
+	// Act
+	fromBlock := uint64(10)
+	logs, sub, err := manager.SubscribeEvents(context.Background(), utils.EventFilter{
+		Addresses: []utils.Address{contract},
+		FromBlock: &fromBlock,
+	})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, uint64(11), readLog(t, logs).BlockNumber)
+	assert.Equal(t, uint64(13), readLog(t, logs).BlockNumber)
+
+	select {
+	case err := <-sub.Err():
+		assert.ErrorContains(t, err, utils.ErrEVMFailedToSubscribe)
+		assert.ErrorContains(t, err, "connection refused")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the stream to fail")
+	}
+	_, ok := <-logs
+	assert.False(t, ok)
+}
@@ -0,0 +1,735 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// FailoverConfig configures how a FailoverClient checks and picks its endpoints.
+type FailoverConfig struct {
+	// HealthCheckInterval is how often the endpoints are checked, 10s by default.
+	HealthCheckInterval time.Duration
+	// MaxBlockLag is how many blocks an endpoint may fall behind the highest block reported
+	// by the others before it is considered unhealthy, 5 by default.
+	MaxBlockLag uint64
+	// Timeout bounds the dial and the calls of each endpoint check, 5s by default.
+	Timeout time.Duration
+}
+
+// failoverEndpoint is an endpoint of a FailoverClient and its last known health.
+type failoverEndpoint struct {
+	url     string
+	client  ClientInterface // Nil until the endpoint has been dialed
+	healthy bool
+}
+
+// FailoverClient is a ClientInterface that routes every call to the first healthy endpoint
+// of a prioritized list. A call failing with a transport error marks its endpoint unhealthy
+// and is retried on the next one, while JSON-RPC errors such as reverts are returned as is.
+//
+// The endpoints are checked in the background with ChainID and BlockNumber. Endpoints that
+// cannot be reached, report another chain or fall behind the chain head are skipped, and
+// calls go back to a higher priority endpoint as soon as a check finds it healthy again.
+type FailoverClient struct {
+	factory ClientFactory
+	config  FailoverConfig
+
+	mu        sync.RWMutex
+	endpoints []*failoverEndpoint
+	chainID   *big.Int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewFailoverClient creates a new FailoverClient instance dialing the endpoints, in priority
+// order, with the factory. Endpoints that cannot be dialed yet are retried on every health
+// check, and an error is returned only when none of them can be dialed.
+func NewFailoverClient(ctx context.Context, factory ClientFactory, urls []string, config FailoverConfig) (*FailoverClient, error) {
+	if len(urls) == 0 {
+		return nil, utils.WrapError(utils.ErrEVMNoHealthyEndpoint, errors.New("no endpoints"))
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = 10 * time.Second
+	}
+	if config.MaxBlockLag == 0 {
+		config.MaxBlockLag = 5
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	c := &FailoverClient{factory: factory, config: config}
+	var dialErrs []error
+	for _, url := range urls {
+		endpoint := &failoverEndpoint{url: url}
+		client, err := factory.DialContext(ctx, url)
+		if err != nil {
+			dialErrs = append(dialErrs, fmt.Errorf("%s: %w", url, err))
+		} else {
+			endpoint.client = client
+		}
+		c.endpoints = append(c.endpoints, endpoint)
+	}
+	if len(dialErrs) == len(urls) {
+		return nil, utils.WrapError(utils.ErrEVMNoHealthyEndpoint, errors.Join(dialErrs...))
+	}
+
+	// Calls before the first check go to the endpoints in priority order
+	_ = c.CheckHealth(ctx)
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go c.run(loopCtx)
+
+	return c, nil
+}
+
+// Endpoint returns the URL of the endpoint calls are currently routed to.
+func (c *FailoverClient) Endpoint() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if endpoint := c.next(nil); endpoint != nil {
+		return endpoint.url
+	}
+	return ""
+}
+
+// CheckHealth checks every endpoint, dialing the ones that could not be dialed before, and
+// updates which of them calls are routed to. An error is returned when none is healthy.
+func (c *FailoverClient) CheckHealth(ctx context.Context) error {
+	type status struct {
+		client  ClientInterface
+		chainID *big.Int
+		block   uint64
+		err     error
+	}
+
+	c.mu.RLock()
+	statuses := make([]status, len(c.endpoints))
+	for i, endpoint := range c.endpoints {
+		statuses[i].client = endpoint.client
+	}
+	c.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+			defer cancel()
+
+			s := &statuses[i]
+			if s.client == nil {
+				if s.client, s.err = c.factory.DialContext(checkCtx, c.endpoints[i].url); s.err != nil {
+					return
+				}
+			}
+			if s.chainID, s.err = s.client.ChainID(checkCtx); s.err != nil {
+				return
+			}
+			s.block, s.err = s.client.BlockNumber(checkCtx)
+		}(i)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var head uint64
+	for i, s := range statuses {
+		switch {
+		case c.endpoints[i].client == nil:
+			c.endpoints[i].client = s.client
+		case s.client != nil && s.client != c.endpoints[i].client:
+			// Another check dialed the endpoint meanwhile
+			s.client.Close()
+		}
+		if s.err != nil {
+			continue
+		}
+		if c.chainID == nil {
+			c.chainID = s.chainID
+		}
+		if s.chainID.Cmp(c.chainID) == 0 {
+			head = max(head, s.block)
+		}
+	}
+
+	var errs []error
+	for i, s := range statuses {
+		endpoint := c.endpoints[i]
+		switch {
+		case s.err != nil:
+			endpoint.healthy = false
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint.url, s.err))
+		case s.chainID.Cmp(c.chainID) != 0:
+			endpoint.healthy = false
+			errs = append(errs, fmt.Errorf("%s: chain ID %s, expected %s", endpoint.url, s.chainID, c.chainID))
+		case head-s.block > c.config.MaxBlockLag:
+			endpoint.healthy = false
+			errs = append(errs, fmt.Errorf("%s: block %d is behind head %d", endpoint.url, s.block, head))
+		default:
+			endpoint.healthy = true
+		}
+	}
+	if len(errs) == len(statuses) {
+		return utils.WrapError(utils.ErrEVMNoHealthyEndpoint, errors.Join(errs...))
+	}
+	return nil
+}
+
+// ChainID retrieves the current chain ID.
+func (c *FailoverClient) ChainID(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.ChainID(ctx)
+		return err
+	})
+	return result, err
+}
+
+// BlockByHash returns the given full block.
+func (c *FailoverClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	var result *types.Block
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.BlockByHash(ctx, hash)
+		return err
+	})
+	return result, err
+}
+
+// BlockByNumber returns a block from the current canonical chain. If number is nil, the
+// latest known block is returned.
+func (c *FailoverClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	var result *types.Block
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.BlockByNumber(ctx, number)
+		return err
+	})
+	return result, err
+}
+
+// BlockNumber returns the most recent block number.
+func (c *FailoverClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.BlockNumber(ctx)
+		return err
+	})
+	return result, err
+}
+
+// PeerCount returns the number of p2p peers as reported by the net_peerCount method.
+func (c *FailoverClient) PeerCount(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.PeerCount(ctx)
+		return err
+	})
+	return result, err
+}
+
+// BlockReceipts returns the receipts of a given block number or hash.
+func (c *FailoverClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var result []*types.Receipt
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.BlockReceipts(ctx, blockNrOrHash)
+		return err
+	})
+	return result, err
+}
+
+// NetworkID returns the network ID for this client.
+func (c *FailoverClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.NetworkID(ctx)
+		return err
+	})
+	return result, err
+}
+
+// BalanceAt returns the wei balance of the given account.
+func (c *FailoverClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result *big.Int
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.BalanceAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// BalanceAtHash returns the wei balance of the given account at the given block hash.
+func (c *FailoverClient) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	var result *big.Int
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.BalanceAtHash(ctx, account, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// StorageAt returns the value of key in the contract storage of the given account.
+func (c *FailoverClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.StorageAt(ctx, account, key, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// StorageAtHash returns the value of key in the contract storage of the given account at the
+// given block hash.
+func (c *FailoverClient) StorageAtHash(ctx context.Context, account common.Address, key common.Hash, blockHash common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.StorageAtHash(ctx, account, key, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// CodeAt returns the contract code of the given account.
+func (c *FailoverClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// CodeAtHash returns the contract code of the given account at the given block hash.
+func (c *FailoverClient) CodeAtHash(ctx context.Context, account common.Address, blockHash common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.CodeAtHash(ctx, account, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// NonceAt returns the account nonce of the given account.
+func (c *FailoverClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	var result uint64
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.NonceAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// NonceAtHash returns the account nonce of the given account at the given block hash.
+func (c *FailoverClient) NonceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (uint64, error) {
+	var result uint64
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.NonceAtHash(ctx, account, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If number is
+// nil, the latest known header is returned.
+func (c *FailoverClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var result *types.Header
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return result, err
+}
+
+// PendingBalanceAt returns the wei balance of the given account in the pending state.
+func (c *FailoverClient) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	var result *big.Int
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.PendingBalanceAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+// PendingStorageAt returns the value of key in the contract storage of the given account in
+// the pending state.
+func (c *FailoverClient) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.PendingStorageAt(ctx, account, key)
+		return err
+	})
+	return result, err
+}
+
+// PendingCodeAt returns the contract code of the given account in the pending state.
+func (c *FailoverClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.PendingCodeAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+// PendingNonceAt returns the account nonce of the given account in the pending state.
+func (c *FailoverClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var result uint64
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.PendingNonceAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+// PendingTransactionCount returns the total number of transactions in the pending state.
+func (c *FailoverClient) PendingTransactionCount(ctx context.Context) (uint, error) {
+	var result uint
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.PendingTransactionCount(ctx)
+		return err
+	})
+	return result, err
+}
+
+// CallContract executes a message call transaction without creating a transaction on the chain.
+func (c *FailoverClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// CallContractAtHash executes a message call transaction at the given block hash.
+func (c *FailoverClient) CallContractAtHash(ctx context.Context, msg ethereum.CallMsg, blockHash common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.CallContractAtHash(ctx, msg, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// PendingCallContract executes a message call transaction against the pending state.
+func (c *FailoverClient) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	var result []byte
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.PendingCallContract(ctx, msg)
+		return err
+	})
+	return result, err
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price.
+func (c *FailoverClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.SuggestGasPrice(ctx)
+		return err
+	})
+	return result, err
+}
+
+// SuggestGasTipCap retrieves the currently suggested gas tip cap.
+func (c *FailoverClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.SuggestGasTipCap(ctx)
+		return err
+	})
+	return result, err
+}
+
+// FeeHistory retrieves the fee market history.
+func (c *FailoverClient) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	var result *ethereum.FeeHistory
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+		return err
+	})
+	return result, err
+}
+
+// EstimateGas estimates the gas needed to execute a specific transaction.
+func (c *FailoverClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	var result uint64
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.EstimateGas(ctx, msg)
+		return err
+	})
+	return result, err
+}
+
+// TransactionByHash returns the transaction with the given hash.
+func (c *FailoverClient) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	var result *types.Transaction
+	var isPending bool
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, isPending, err = client.TransactionByHash(ctx, txHash)
+		return err
+	})
+	return result, isPending, err
+}
+
+// TransactionReceipt returns the receipt of a transaction by transaction hash.
+func (c *FailoverClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var result *types.Receipt
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return result, err
+}
+
+// SendTransaction injects a signed transaction into the pending pool for execution.
+// Sending the same signed transaction to another endpoint cannot execute it twice. Since an
+// endpoint failing with a transport error may still have broadcast it, the next endpoint
+// already knowing the transaction, or rejecting it with a nonce too low while it knows its
+// hash, counts as a success.
+func (c *FailoverClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	attempt := 0
+	return c.do(ctx, func(client ClientInterface) error {
+		attempt++
+		err := client.SendTransaction(ctx, tx)
+		switch {
+		case err == nil || attempt == 1:
+			return err
+		case containsAny(err, knownTransactionErrors):
+			return nil
+		case strings.Contains(err.Error(), "nonce too low"):
+			if _, _, lookupErr := client.TransactionByHash(ctx, tx.Hash()); lookupErr == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// FilterLogs executes a filter query.
+func (c *FailoverClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var result []types.Log
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.FilterLogs(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query. The
+// subscription stays on the endpoint it was started on.
+func (c *FailoverClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	var result ethereum.Subscription
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.SubscribeFilterLogs(ctx, q, ch)
+		return err
+	})
+	return result, err
+}
+
+// SubscribeNewHead subscribes to notifications about the current blockchain head. The
+// subscription stays on the endpoint it was started on.
+func (c *FailoverClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	var result ethereum.Subscription
+	err := c.do(ctx, func(client ClientInterface) (err error) {
+		result, err = client.SubscribeNewHead(ctx, ch)
+		return err
+	})
+	return result, err
+}
+
+// CallContext sends a raw JSON-RPC request, failing over like the other calls.
+func (c *FailoverClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return c.do(ctx, func(client ClientInterface) error {
+		caller := rpcCaller(client)
+		if caller == nil {
+			return errRPCClientUnavailable
+		}
+		return caller.CallContext(ctx, result, method, args...)
+	})
+}
+
+// BatchCallContext sends raw JSON-RPC requests in one batch, failing over when the request
+// itself fails.
+func (c *FailoverClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return c.do(ctx, func(client ClientInterface) error {
+		caller := rpcCaller(client)
+		if caller == nil {
+			return errRPCClientUnavailable
+		}
+		return caller.BatchCallContext(ctx, b)
+	})
+}
+
+// Client returns the RPC client of the endpoint calls are currently routed to.
+func (c *FailoverClient) Client() *rpc.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if endpoint := c.next(nil); endpoint != nil {
+		return endpoint.client.Client()
+	}
+	return nil
+}
+
+// Close stops the health checks and closes the clients of every endpoint.
+func (c *FailoverClient) Close() {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+		c.cancel = nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, endpoint := range c.endpoints {
+		if endpoint.client != nil {
+			endpoint.client.Close()
+			endpoint.client = nil
+		}
+		endpoint.healthy = false
+	}
+}
+
+// do runs call on the endpoint calls are routed to. While it fails with a transport error,
+// the endpoint is marked unhealthy and call is retried on the next endpoint.
+func (c *FailoverClient) do(ctx context.Context, call func(client ClientInterface) error) error {
+	tried := make(map[*failoverEndpoint]bool, len(c.endpoints))
+	var errs []error
+	for {
+		c.mu.RLock()
+		endpoint := c.next(tried)
+		var client ClientInterface
+		if endpoint != nil {
+			client = endpoint.client
+		}
+		c.mu.RUnlock()
+
+		if endpoint == nil {
+			if len(errs) == 0 {
+				return utils.WrapError(utils.ErrEVMNoHealthyEndpoint)
+			}
+			return utils.WrapError(utils.ErrEVMNoHealthyEndpoint, errors.Join(errs...))
+		}
+		tried[endpoint] = true
+
+		err := call(client)
+		if err == nil || ctx.Err() != nil || !isTransportError(err) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint.url, err))
+
+		c.mu.Lock()
+		endpoint.healthy = false
+		c.mu.Unlock()
+	}
+}
+
+// next returns the first healthy endpoint not tried yet in priority order. When none is
+// healthy, the first dialed endpoint not tried yet is returned instead, so that calls are
+// still attempted while every endpoint is failing. It must be called with the lock held.
+func (c *FailoverClient) next(tried map[*failoverEndpoint]bool) *failoverEndpoint {
+	var fallback *failoverEndpoint
+	for _, endpoint := range c.endpoints {
+		if endpoint.client == nil || tried[endpoint] {
+			continue
+		}
+		if endpoint.healthy {
+			return endpoint
+		}
+		if fallback == nil {
+			fallback = endpoint
+		}
+	}
+	return fallback
+}
+
+// run checks the endpoints on every health check interval until the client is closed.
+func (c *FailoverClient) run(ctx context.Context) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = c.CheckHealth(ctx)
+		}
+	}
+}
+
+// isTransportError reports whether err means the endpoint could not serve the call, as
+// opposed to the call itself failing, so that it is worth retrying on another endpoint.
+func isTransportError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == 429
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, rpc.ErrClientQuit) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// FailoverClientFactory is a ClientFactory that dials FailoverClients over several endpoints.
+type FailoverClientFactory struct {
+	// Endpoints are the fallback endpoints, in priority order, used after the URL the client
+	// is dialed with.
+	Endpoints []string
+	// Factory dials the clients of every endpoint, an EthClientFactory when nil.
+	Factory ClientFactory
+	// Config configures how the clients check and pick their endpoints.
+	Config FailoverConfig
+}
+
+// DialContext dials a new FailoverClient with url as its primary endpoint.
+func (f *FailoverClientFactory) DialContext(ctx context.Context, url string) (ClientInterface, error) {
+	factory := f.Factory
+	if factory == nil {
+		factory = &EthClientFactory{}
+	}
+
+	urls := []string{url}
+	seen := map[string]bool{url: true}
+	for _, endpoint := range f.Endpoints {
+		if !seen[endpoint] {
+			seen[endpoint] = true
+			urls = append(urls, endpoint)
+		}
+	}
+	return NewFailoverClient(ctx, factory, urls, f.Config)
+}
@@ -0,0 +1,280 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPrimaryURL = "http://primary:8545"
+	testBackupURL  = "http://backup:8545"
+)
+
+// errConnectionRefused is the error a call to an unreachable endpoint fails with.
+var errConnectionRefused = &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+// testRevertError is a JSON-RPC error returned by a reverted call.
+type testRevertError struct{}
+
+func (testRevertError) Error() string  { return "execution reverted" }
+func (testRevertError) ErrorCode() int { return 3 }
+
+// expectHealth sets up the calls of one health check of an endpoint.
+func expectHealth(mockClient *mock_evm.MockClientInterface, chainID int64, block uint64) {
+	mockClient.EXPECT().ChainID(gomock.Any()).Return(big.NewInt(chainID), nil)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(block, nil)
+}
+
+// newFailoverFactory returns a factory dialing failover clients over a mock primary and backup endpoint.
+func newFailoverFactory(ctrl *gomock.Controller) (*evm.FailoverClientFactory, *mock_evm.MockClientFactory) {
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	return &evm.FailoverClientFactory{
+		Endpoints: []string{testBackupURL, testPrimaryURL},
+		Factory:   mockClientFactory,
+		Config:    evm.FailoverConfig{HealthCheckInterval: time.Hour},
+	}, mockClientFactory
+}
+
+// TestFailoverClient_TransportErrors tests that calls fail over on transport errors and fail back once the primary recovers.
+// go test -v -cover ./pkg/evm -run TestFailoverClient_TransportErrors
+func TestFailoverClient_TransportErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	factory, mockClientFactory := newFailoverFactory(ctrl)
+	primary := mock_evm.NewMockClientInterface(ctrl)
+	backup := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testPrimaryURL).Return(primary, nil)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testBackupURL).Return(backup, nil)
+	expectHealth(primary, 1, 100)
+	expectHealth(backup, 1, 100)
+
+	dialed, err := factory.DialContext(context.Background(), testPrimaryURL)
+	assert.NoError(t, err)
+	client := dialed.(*evm.FailoverClient)
+	assert.Equal(t, testPrimaryURL, client.Endpoint())
+
+	// Transport errors fail over to the backup, which keeps serving calls
+	account := common.HexToAddress(generateRandomAddress().String())
+	primary.EXPECT().BalanceAt(gomock.Any(), account, nil).Return(nil, errConnectionRefused)
+	backup.EXPECT().BalanceAt(gomock.Any(), account, nil).Return(big.NewInt(42), nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		balance, err := client.BalanceAt(context.Background(), account, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewInt(42), balance)
+		assert.Equal(t, testBackupURL, client.Endpoint())
+	}
+
+	// Calls fail back once a check finds the primary healthy again
+	expectHealth(primary, 1, 101)
+	expectHealth(backup, 1, 101)
+	assert.NoError(t, client.CheckHealth(context.Background()))
+	assert.Equal(t, testPrimaryURL, client.Endpoint())
+
+	// Server errors fail over too
+	primary.EXPECT().BlockNumber(gomock.Any()).Return(uint64(0), rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"})
+	backup.EXPECT().BlockNumber(gomock.Any()).Return(uint64(101), nil)
+	block, err := client.BlockNumber(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(101), block)
+
+	// Errors of the call itself are returned without failing over
+	expectHealth(primary, 1, 101)
+	expectHealth(backup, 1, 101)
+	assert.NoError(t, client.CheckHealth(context.Background()))
+	primary.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(nil, testRevertError{})
+	primary.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(0), rpc.HTTPError{StatusCode: 400})
+	primary.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).Return(errors.New("nonce too low"))
+
+	_, err = client.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	assert.ErrorContains(t, err, "execution reverted")
+	_, err = client.EstimateGas(context.Background(), ethereum.CallMsg{})
+	assert.Error(t, err)
+	assert.ErrorContains(t, client.SendTransaction(context.Background(), nil), "nonce too low")
+	assert.Equal(t, testPrimaryURL, client.Endpoint())
+
+	// Every endpoint failing is reported
+	primary.EXPECT().ChainID(gomock.Any()).Return(nil, errConnectionRefused)
+	backup.EXPECT().ChainID(gomock.Any()).Return(nil, errConnectionRefused)
+	_, err = client.ChainID(context.Background())
+	assert.ErrorContains(t, err, utils.ErrEVMNoHealthyEndpoint)
+	assert.ErrorContains(t, err, "connection refused")
+
+	primary.EXPECT().Close()
+	backup.EXPECT().Close()
+	client.Close()
+}
+
+// TestFailoverClient_SendTransaction tests that a transaction broadcast by an endpoint that failed is not reported as failed by the next.
+// go test -v -cover ./pkg/evm -run TestFailoverClient_SendTransaction
+func TestFailoverClient_SendTransaction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	factory, mockClientFactory := newFailoverFactory(ctrl)
+	primary := mock_evm.NewMockClientInterface(ctrl)
+	backup := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testPrimaryURL).Return(primary, nil)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testBackupURL).Return(backup, nil)
+	expectHealth(primary, 1, 100)
+	expectHealth(backup, 1, 100)
+
+	dialed, err := factory.DialContext(context.Background(), testPrimaryURL)
+	assert.NoError(t, err)
+	client := dialed.(*evm.FailoverClient)
+	defer func() {
+		primary.EXPECT().Close()
+		backup.EXPECT().Close()
+		client.Close()
+	}()
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tx := newSignedTransfer(t, privateKey, 1)
+	primary.EXPECT().SendTransaction(gomock.Any(), tx).Return(errConnectionRefused).Times(3)
+	failBack := func() {
+		expectHealth(primary, 1, 100)
+		expectHealth(backup, 1, 100)
+		assert.NoError(t, client.CheckHealth(context.Background()))
+	}
+
+	// The backup already got the transaction from the primary's peers
+	backup.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("already known"))
+	assert.NoError(t, client.SendTransaction(context.Background(), tx))
+
+	// Or already mined it
+	failBack()
+	backup.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("nonce too low"))
+	backup.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+	assert.NoError(t, client.SendTransaction(context.Background(), tx))
+
+	// A nonce used by another transaction is still reported
+	failBack()
+	backup.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("nonce too low"))
+	backup.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(nil, false, ethereum.NotFound)
+	assert.ErrorContains(t, client.SendTransaction(context.Background(), tx), "nonce too low")
+
+	// Without a failover, the first endpoint's answer is returned as is
+	failBack()
+	primary.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("already known"))
+	assert.ErrorContains(t, client.SendTransaction(context.Background(), tx), "already known")
+}
+
+// TestFailoverClient_CheckHealth tests that endpoints behind the chain head or on another chain are skipped.
+// go test -v -cover ./pkg/evm -run TestFailoverClient_CheckHealth
+func TestFailoverClient_CheckHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	factory, mockClientFactory := newFailoverFactory(ctrl)
+	primary := mock_evm.NewMockClientInterface(ctrl)
+	backup := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testPrimaryURL).Return(primary, nil)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testBackupURL).Return(backup, nil)
+
+	// A primary lagging behind the backup is skipped
+	expectHealth(primary, 1, 90)
+	expectHealth(backup, 1, 100)
+
+	dialed, err := factory.DialContext(context.Background(), testPrimaryURL)
+	assert.NoError(t, err)
+	client := dialed.(*evm.FailoverClient)
+	assert.Equal(t, testBackupURL, client.Endpoint())
+
+	// A small lag is tolerated
+	expectHealth(primary, 1, 97)
+	expectHealth(backup, 1, 100)
+	assert.NoError(t, client.CheckHealth(context.Background()))
+	assert.Equal(t, testPrimaryURL, client.Endpoint())
+
+	// An endpoint on another chain is never used
+	expectHealth(primary, 1, 100)
+	expectHealth(backup, 5, 200)
+	assert.NoError(t, client.CheckHealth(context.Background()))
+	assert.Equal(t, testPrimaryURL, client.Endpoint())
+
+	// Unreachable endpoints are skipped
+	primary.EXPECT().ChainID(gomock.Any()).Return(nil, errConnectionRefused)
+	expectHealth(backup, 1, 101)
+	assert.NoError(t, client.CheckHealth(context.Background()))
+	assert.Equal(t, testBackupURL, client.Endpoint())
+
+	// And reported when none is left
+	primary.EXPECT().ChainID(gomock.Any()).Return(nil, errConnectionRefused)
+	expectHealth(backup, 5, 201)
+	err = client.CheckHealth(context.Background())
+	assert.ErrorContains(t, err, utils.ErrEVMNoHealthyEndpoint)
+	assert.ErrorContains(t, err, "chain ID 5, expected 1")
+
+	primary.EXPECT().Close()
+	backup.EXPECT().Close()
+	client.Close()
+}
+
+// TestFailoverClientFactory_DialContext tests dialing failover clients while endpoints are down.
+// go test -v -cover ./pkg/evm -run TestFailoverClientFactory_DialContext
+func TestFailoverClientFactory_DialContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	factory, mockClientFactory := newFailoverFactory(ctrl)
+	primary := mock_evm.NewMockClientInterface(ctrl)
+	backup := mock_evm.NewMockClientInterface(ctrl)
+
+	// Failing to dial every endpoint is reported
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testPrimaryURL).Return(nil, errConnectionRefused)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testBackupURL).Return(nil, errConnectionRefused)
+	_, err := factory.DialContext(context.Background(), testPrimaryURL)
+	assert.ErrorContains(t, err, utils.ErrEVMNoHealthyEndpoint)
+
+	// Endpoints that cannot be dialed yet are redialed by the health checks
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testPrimaryURL).Return(nil, errConnectionRefused).Times(2)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testBackupURL).Return(backup, nil)
+	expectHealth(backup, 1, 100)
+
+	dialed, err := factory.DialContext(context.Background(), testPrimaryURL)
+	assert.NoError(t, err)
+	client := dialed.(*evm.FailoverClient)
+	assert.Equal(t, testBackupURL, client.Endpoint())
+
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testPrimaryURL).Return(primary, nil)
+	expectHealth(primary, 1, 100)
+	expectHealth(backup, 1, 100)
+	assert.NoError(t, client.CheckHealth(context.Background()))
+	assert.Equal(t, testPrimaryURL, client.Endpoint())
+
+	// Managers use failover clients as a drop-in
+	manager := evm.NewManager(testPrimaryURL, nil, factory, utils.Ethereum).(*evm.Manager)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testPrimaryURL).Return(primary, nil)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), testBackupURL).Return(backup, nil)
+	expectHealth(primary, 1, 100)
+	expectHealth(backup, 1, 100)
+	assert.NoError(t, manager.Start(context.Background()))
+
+	primary.EXPECT().BalanceAt(gomock.Any(), gomock.Any(), nil).Return(nil, errConnectionRefused)
+	backup.EXPECT().BalanceAt(gomock.Any(), gomock.Any(), nil).Return(big.NewInt(7), nil)
+	balance, err := manager.GetBalance(context.Background(), generateRandomAddress(), utils.Token{Type: utils.Native})
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(7), balance)
+
+	primary.EXPECT().Close().Times(2)
+	backup.EXPECT().Close().Times(2)
+	client.Close()
+	manager.Stop(context.Background())
+}
@@ -0,0 +1,271 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// FeeSpeed selects how aggressively a fee suggestion prices a transaction.
+type FeeSpeed int
+
+const (
+	// FeeSpeedSlow prices a transaction at the low end of recent tips.
+	FeeSpeedSlow FeeSpeed = iota
+	// FeeSpeedStandard prices a transaction at the median of recent tips.
+	FeeSpeedStandard
+	// FeeSpeedFast prices a transaction at the high end of recent tips.
+	FeeSpeedFast
+)
+
+// FeeSuggestion holds the suggested fees of a single speed tier.
+type FeeSuggestion struct {
+	// GasPrice is the price to use for a legacy transaction.
+	GasPrice *big.Int
+	// MaxFeePerGas and MaxPriorityFeePerGas are the dynamic fees to use for an EIP-1559
+	// transaction. They are nil on chains without a base fee.
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// Apply sets the suggested fees on the transaction payload, using dynamic fees when the
+// chain supports them and a legacy gas price otherwise.
+func (s FeeSuggestion) Apply(tx utils.Transaction) {
+	if s.MaxFeePerGas != nil && s.MaxPriorityFeePerGas != nil {
+		tx.SetPayload("maxFeePerGas", new(big.Int).Set(s.MaxFeePerGas))
+		tx.SetPayload("maxPriorityFeePerGas", new(big.Int).Set(s.MaxPriorityFeePerGas))
+		return
+	}
+	tx.SetPayload("gasPrice", new(big.Int).Set(s.GasPrice))
+}
+
+// FeeSuggestions holds the suggested fees for every speed tier.
+type FeeSuggestions struct {
+	// BaseFee is the expected base fee of the next block, nil on chains without a base fee.
+	BaseFee  *big.Int
+	Slow     FeeSuggestion
+	Standard FeeSuggestion
+	Fast     FeeSuggestion
+}
+
+// Speed returns the suggestion for the given speed tier.
+func (s *FeeSuggestions) Speed(speed FeeSpeed) FeeSuggestion {
+	switch speed {
+	case FeeSpeedSlow:
+		return s.Slow
+	case FeeSpeedFast:
+		return s.Fast
+	default:
+		return s.Standard
+	}
+}
+
+// FeeOracleConfig configures how a FeeOracle samples recent blocks and bounds its suggestions.
+// Zero values fall back to the defaults, and nil bounds are not enforced.
+type FeeOracleConfig struct {
+	// BlockCount is the number of recent blocks sampled, 20 by default.
+	BlockCount uint64
+	// Percentiles are the reward percentiles used for the slow, standard and fast tiers,
+	// 10, 50 and 90 by default.
+	Percentiles [3]float64
+	// BaseFeeMultiplier is the headroom left in the fee cap for the base fee to grow, 2 by default.
+	BaseFeeMultiplier uint64
+
+	MinPriorityFee *big.Int
+	MaxPriorityFee *big.Int
+	MinGasPrice    *big.Int
+	MaxGasPrice    *big.Int
+	MaxFeePerGas   *big.Int
+}
+
+// DefaultFeeOracleConfig returns the fee oracle configuration for the network, including the
+// fee floors its validators enforce.
+func DefaultFeeOracleConfig(network utils.Blockchain) FeeOracleConfig {
+	config := FeeOracleConfig{
+		BlockCount:        20,
+		Percentiles:       [3]float64{10, 50, 90},
+		BaseFeeMultiplier: 2,
+	}
+
+	switch network {
+	case utils.Polygon:
+		// Polygon validators reject transactions tipping less than 30 gwei
+		config.MinPriorityFee = big.NewInt(30 * params.GWei)
+		config.MinGasPrice = big.NewInt(30 * params.GWei)
+	}
+
+	return config
+}
+
+// merge returns the configuration with the fields set in the override replacing its own.
+func (c FeeOracleConfig) merge(override FeeOracleConfig) FeeOracleConfig {
+	if override.BlockCount != 0 {
+		c.BlockCount = override.BlockCount
+	}
+	if override.Percentiles != [3]float64{} {
+		c.Percentiles = override.Percentiles
+	}
+	if override.BaseFeeMultiplier != 0 {
+		c.BaseFeeMultiplier = override.BaseFeeMultiplier
+	}
+	if override.MinPriorityFee != nil {
+		c.MinPriorityFee = override.MinPriorityFee
+	}
+	if override.MaxPriorityFee != nil {
+		c.MaxPriorityFee = override.MaxPriorityFee
+	}
+	if override.MinGasPrice != nil {
+		c.MinGasPrice = override.MinGasPrice
+	}
+	if override.MaxGasPrice != nil {
+		c.MaxGasPrice = override.MaxGasPrice
+	}
+	if override.MaxFeePerGas != nil {
+		c.MaxFeePerGas = override.MaxFeePerGas
+	}
+	return c
+}
+
+// FeeOracle suggests transaction fees from the base fees and tips paid in recent blocks.
+type FeeOracle struct {
+	client ClientInterface
+	config FeeOracleConfig
+}
+
+// NewFeeOracle creates a new FeeOracle instance.
+func NewFeeOracle(client ClientInterface, config FeeOracleConfig) *FeeOracle {
+	if config.BlockCount == 0 {
+		config.BlockCount = 20
+	}
+	if config.Percentiles == [3]float64{} {
+		config.Percentiles = [3]float64{10, 50, 90}
+	}
+	if config.BaseFeeMultiplier == 0 {
+		config.BaseFeeMultiplier = 2
+	}
+	return &FeeOracle{client: client, config: config}
+}
+
+// SuggestFees returns slow, standard and fast fee suggestions.
+//
+// The tiers are the median, across recently sampled blocks, of the configured reward
+// percentiles. Empty blocks are skipped. When the node does not support eth_feeHistory or
+// the sampled blocks are empty, every tier falls back to the node's own suggestion.
+func (o *FeeOracle) SuggestFees(ctx context.Context) (*FeeSuggestions, error) {
+	baseFee, tips, err := o.sample(ctx)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMFailedToSuggestFees, err)
+	}
+
+	// Chains without a base fee are priced with a legacy gas price only
+	if baseFee == nil || baseFee.Sign() == 0 {
+		if tips == nil {
+			gasPrice, err := o.client.SuggestGasPrice(ctx)
+			if err != nil {
+				return nil, utils.WrapError(utils.ErrEVMFailedToSuggestFees, fmt.Errorf("failed to suggest gas price: %w", err))
+			}
+			tips = []*big.Int{gasPrice, gasPrice, gasPrice}
+		}
+		return &FeeSuggestions{
+			Slow:     FeeSuggestion{GasPrice: o.clampGasPrice(tips[0])},
+			Standard: FeeSuggestion{GasPrice: o.clampGasPrice(tips[1])},
+			Fast:     FeeSuggestion{GasPrice: o.clampGasPrice(tips[2])},
+		}, nil
+	}
+
+	if tips == nil {
+		tipCap, err := o.client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrEVMFailedToSuggestFees, fmt.Errorf("failed to suggest gas tip cap: %w", err))
+		}
+		tips = []*big.Int{tipCap, tipCap, tipCap}
+	}
+
+	return &FeeSuggestions{
+		BaseFee:  baseFee,
+		Slow:     o.dynamicSuggestion(baseFee, tips[0]),
+		Standard: o.dynamicSuggestion(baseFee, tips[1]),
+		Fast:     o.dynamicSuggestion(baseFee, tips[2]),
+	}, nil
+}
+
+// sample returns the next block's base fee and the tips of every tier. The tips are nil
+// when recent blocks could not be sampled.
+func (o *FeeOracle) sample(ctx context.Context) (*big.Int, []*big.Int, error) {
+	history, err := o.client.FeeHistory(ctx, o.config.BlockCount, nil, o.config.Percentiles[:])
+	if err == nil && history != nil && len(history.BaseFee) > 0 {
+		// The last base fee is the one of the block after the sampled range
+		return history.BaseFee[len(history.BaseFee)-1], tierTips(history.Reward, history.GasUsedRatio), nil
+	}
+
+	// Fall back to the latest header when eth_feeHistory is not available
+	header, err := o.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch latest header: %w", err)
+	}
+	return header.BaseFee, nil, nil
+}
+
+// dynamicSuggestion prices a tier from the base fee and its tip, within the configured bounds.
+func (o *FeeOracle) dynamicSuggestion(baseFee, tip *big.Int) FeeSuggestion {
+	tip = clamp(tip, o.config.MinPriorityFee, o.config.MaxPriorityFee)
+
+	feeCap := new(big.Int).Mul(baseFee, new(big.Int).SetUint64(o.config.BaseFeeMultiplier))
+	feeCap.Add(feeCap, tip)
+	feeCap = clamp(feeCap, nil, o.config.MaxFeePerGas)
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	gasPrice := new(big.Int).Add(baseFee, tip)
+	return FeeSuggestion{
+		GasPrice:             o.clampGasPrice(gasPrice),
+		MaxFeePerGas:         feeCap,
+		MaxPriorityFeePerGas: tip,
+	}
+}
+
+// clampGasPrice bounds a legacy gas price to the configured floor and ceiling.
+func (o *FeeOracle) clampGasPrice(gasPrice *big.Int) *big.Int {
+	return clamp(gasPrice, o.config.MinGasPrice, o.config.MaxGasPrice)
+}
+
+// tierTips returns the median reward of every percentile across the non-empty blocks, or nil
+// when every block is empty.
+func tierTips(rewards [][]*big.Int, gasUsedRatios []float64) []*big.Int {
+	tips := make([]*big.Int, 3)
+	for tier := range tips {
+		var samples []*big.Int
+		for i, reward := range rewards {
+			if i < len(gasUsedRatios) && gasUsedRatios[i] == 0 {
+				continue
+			}
+			if tier < len(reward) && reward[tier] != nil {
+				samples = append(samples, reward[tier])
+			}
+		}
+		if len(samples) == 0 {
+			return nil
+		}
+
+		sort.Slice(samples, func(i, j int) bool { return samples[i].Cmp(samples[j]) < 0 })
+		tips[tier] = new(big.Int).Set(samples[len(samples)/2])
+	}
+	return tips
+}
+
+// clamp returns a copy of value bounded to [min, max]. Nil bounds are ignored.
+func clamp(value, min, max *big.Int) *big.Int {
+	switch {
+	case min != nil && value.Cmp(min) < 0:
+		return new(big.Int).Set(min)
+	case max != nil && value.Cmp(max) > 0:
+		return new(big.Int).Set(max)
+	default:
+		return new(big.Int).Set(value)
+	}
+}
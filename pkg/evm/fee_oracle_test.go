@@ -0,0 +1,237 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFeeOracle_SuggestFees_Tiers tests that the tiers are the median rewards of the non-empty sampled blocks.
+// go test -v -cover ./pkg/evm -run TestFeeOracle_SuggestFees_Tiers
+func TestFeeOracle_SuggestFees_Tiers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	oracle := evm.NewFeeOracle(mockClient, evm.DefaultFeeOracleConfig(utils.Ethereum))
+
+	mockClient.EXPECT().FeeHistory(gomock.Any(), uint64(20), nil, []float64{10, 50, 90}).Return(&ethereum.FeeHistory{
+		BaseFee: []*big.Int{big.NewInt(100), big.NewInt(100), big.NewInt(100), big.NewInt(100), big.NewInt(110)},
+		Reward: [][]*big.Int{
+			{big.NewInt(1), big.NewInt(5), big.NewInt(9)},
+			{big.NewInt(0), big.NewInt(0), big.NewInt(0)}, // Empty block
+			{big.NewInt(3), big.NewInt(4), big.NewInt(20)},
+			{big.NewInt(2), big.NewInt(6), big.NewInt(10)},
+		},
+		GasUsedRatio: []float64{0.4, 0, 0.9, 0.6},
+	}, nil)
+
+	// Act
+	suggestions, err := oracle.SuggestFees(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(110), suggestions.BaseFee)
+
+	assert.Equal(t, big.NewInt(2), suggestions.Slow.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(222), suggestions.Slow.MaxFeePerGas) // 2 * BaseFee (110) + tip (2)
+	assert.Equal(t, big.NewInt(112), suggestions.Slow.GasPrice)     // BaseFee (110) + tip (2)
+
+	assert.Equal(t, big.NewInt(5), suggestions.Standard.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(225), suggestions.Standard.MaxFeePerGas)
+
+	assert.Equal(t, big.NewInt(10), suggestions.Fast.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(230), suggestions.Fast.MaxFeePerGas)
+	assert.Equal(t, suggestions.Fast, suggestions.Speed(evm.FeeSpeedFast))
+}
+
+// TestFeeOracle_SuggestFees_Bounds tests that the network floors and configured ceilings are enforced.
+// go test -v -cover ./pkg/evm -run TestFeeOracle_SuggestFees_Bounds
+func TestFeeOracle_SuggestFees_Bounds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	config := evm.DefaultFeeOracleConfig(utils.Polygon)
+	config.MaxFeePerGas = big.NewInt(100 * params.GWei)
+	oracle := evm.NewFeeOracle(mockClient, config)
+
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(&ethereum.FeeHistory{
+		BaseFee: []*big.Int{big.NewInt(params.GWei), big.NewInt(params.GWei)},
+		Reward: [][]*big.Int{
+			{big.NewInt(params.GWei), big.NewInt(35 * params.GWei), big.NewInt(500 * params.GWei)},
+		},
+		GasUsedRatio: []float64{0.5},
+	}, nil)
+
+	// Act
+	suggestions, err := oracle.SuggestFees(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+
+	// The slow tip is raised to Polygon's minimum tip
+	assert.Equal(t, big.NewInt(30*params.GWei), suggestions.Slow.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(32*params.GWei), suggestions.Slow.MaxFeePerGas)
+
+	assert.Equal(t, big.NewInt(35*params.GWei), suggestions.Standard.MaxPriorityFeePerGas)
+
+	// The fast fee cap is capped, and the tip with it
+	assert.Equal(t, big.NewInt(100*params.GWei), suggestions.Fast.MaxFeePerGas)
+	assert.Equal(t, big.NewInt(100*params.GWei), suggestions.Fast.MaxPriorityFeePerGas)
+}
+
+// TestFeeOracle_SuggestFees_Legacy tests the gas price suggestions of a chain without a base fee.
+// go test -v -cover ./pkg/evm -run TestFeeOracle_SuggestFees_Legacy
+func TestFeeOracle_SuggestFees_Legacy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	oracle := evm.NewFeeOracle(mockClient, evm.FeeOracleConfig{MinGasPrice: big.NewInt(2)})
+
+	// Rewards are the full gas prices paid on chains without a base fee
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(0), big.NewInt(0)},
+		Reward:       [][]*big.Int{{big.NewInt(1), big.NewInt(3), big.NewInt(5)}},
+		GasUsedRatio: []float64{0.5},
+	}, nil)
+
+	// Act
+	suggestions, err := oracle.SuggestFees(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Nil(t, suggestions.BaseFee)
+	assert.Equal(t, big.NewInt(2), suggestions.Slow.GasPrice)
+	assert.Equal(t, big.NewInt(3), suggestions.Standard.GasPrice)
+	assert.Equal(t, big.NewInt(5), suggestions.Fast.GasPrice)
+	assert.Nil(t, suggestions.Fast.MaxFeePerGas)
+
+	// Applying a legacy suggestion sets the gas price
+	txType := utils.Transfer
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(1), &txType,
+		nil, nil, nil, 21000, nil, big.NewInt(56), 1, nil)
+	suggestions.Standard.Apply(tx)
+	assert.Equal(t, big.NewInt(3), tx.Payload()["gasPrice"])
+	assert.NoError(t, tx.Validate())
+}
+
+// TestFeeOracle_SuggestFees_Fallback tests that the node's suggestions are used when blocks cannot be sampled.
+// go test -v -cover ./pkg/evm -run TestFeeOracle_SuggestFees_Fallback
+func TestFeeOracle_SuggestFees_Fallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	oracle := evm.NewFeeOracle(mockClient, evm.FeeOracleConfig{})
+
+	// Only empty blocks were sampled
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(10), big.NewInt(9)},
+		Reward:       [][]*big.Int{{big.NewInt(0), big.NewInt(0), big.NewInt(0)}},
+		GasUsedRatio: []float64{0},
+	}, nil)
+	mockClient.EXPECT().SuggestGasTipCap(gomock.Any()).Return(big.NewInt(4), nil)
+
+	suggestions, err := oracle.SuggestFees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(4), suggestions.Slow.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(22), suggestions.Fast.MaxFeePerGas)
+
+	// eth_feeHistory is not supported and the header cannot be fetched either
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(nil, errors.New("method not found"))
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), nil).Return(nil, errors.New("connection refused"))
+
+	_, err = oracle.SuggestFees(context.Background())
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToSuggestFees)
+	assert.ErrorContains(t, err, "connection refused")
+
+	// eth_feeHistory is not supported on a chain with a base fee
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(nil, errors.New("method not found"))
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), nil).Return(&types.Header{BaseFee: big.NewInt(7)}, nil)
+	mockClient.EXPECT().SuggestGasTipCap(gomock.Any()).Return(big.NewInt(1), nil)
+
+	suggestions, err = oracle.SuggestFees(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(15), suggestions.Standard.MaxFeePerGas)
+}
+
+// TestManager_PrepareTransaction_FeeSpeed tests that PrepareTransaction prices transactions at the configured speed.
+// go test -v -cover ./pkg/evm -run TestManager_PrepareTransaction_FeeSpeed
+func TestManager_PrepareTransaction_FeeSpeed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", nil, mockClientFactory, utils.Ethereum,
+		evm.WithFeeSpeed(evm.FeeSpeedFast),
+		evm.WithFeeOracleConfig(evm.FeeOracleConfig{BlockCount: 5, Percentiles: [3]float64{25, 50, 75}})).(*evm.Manager)
+	manager.Start(context.Background())
+
+	txType := utils.Transfer
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(1000), &txType,
+		nil, nil, nil, 21000, nil, big.NewInt(1), 1, nil)
+
+	mockClient.EXPECT().FeeHistory(gomock.Any(), uint64(5), nil, []float64{25, 50, 75}).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(100), big.NewInt(100)},
+		Reward:       [][]*big.Int{{big.NewInt(1), big.NewInt(2), big.NewInt(3)}},
+		GasUsedRatio: []float64{0.5},
+	}, nil).Times(2)
+
+	// Act
+	err := manager.PrepareTransaction(context.Background(), tx)
+	suggestions, suggestErr := manager.SuggestFees(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, suggestErr)
+	assert.Equal(t, big.NewInt(3), tx.Payload()["maxPriorityFeePerGas"])
+	assert.Equal(t, big.NewInt(203), tx.Payload()["maxFeePerGas"])
+	assert.Equal(t, suggestions.Fast.MaxFeePerGas, tx.Payload()["maxFeePerGas"])
+}
+
+// TestManager_SuggestFees_ConfigOverride tests that a fee oracle configuration only overrides the network defaults it sets.
+// go test -v -cover ./pkg/evm -run TestManager_SuggestFees_ConfigOverride
+func TestManager_SuggestFees_ConfigOverride(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", nil, mockClientFactory, utils.Polygon,
+		evm.WithFeeOracleConfig(evm.FeeOracleConfig{BlockCount: 5, MaxFeePerGas: big.NewInt(100 * params.GWei)})).(*evm.Manager)
+	assert.NoError(t, manager.Start(context.Background()))
+
+	mockClient.EXPECT().FeeHistory(gomock.Any(), uint64(5), nil, []float64{10, 50, 90}).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(params.GWei), big.NewInt(params.GWei)},
+		Reward:       [][]*big.Int{{big.NewInt(1), big.NewInt(2), big.NewInt(500 * params.GWei)}},
+		GasUsedRatio: []float64{0.5},
+	}, nil)
+
+	// Act
+	suggestions, err := manager.SuggestFees(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+
+	// Polygon's minimum tip is kept along with the overridden cap
+	assert.Equal(t, big.NewInt(30*params.GWei), suggestions.Slow.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(30*params.GWei), suggestions.Standard.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(100*params.GWei), suggestions.Fast.MaxFeePerGas)
+}
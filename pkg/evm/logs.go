@@ -0,0 +1,159 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"golang.org/x/sync/errgroup"
+)
+
+// LogQueryConfig configures how GetLogs splits large block ranges.
+type LogQueryConfig struct {
+	// ChunkSize is the number of blocks queried at once, 2000 by default.
+	ChunkSize uint64
+	// Concurrency is the maximum number of chunks queried in parallel, 4 by default.
+	Concurrency int
+}
+
+// logRangeErrors are fragments of the errors providers return when a log query spans too
+// many blocks or matches too many logs.
+var logRangeErrors = []string{
+	"query returned more than",
+	"too many results",
+	"too many logs",
+	"response size",
+	"block range",
+	"range too large",
+	"range is too large",
+	"exceed maximum block range",
+}
+
+// GetLogs returns the logs matching the filter between its FromBlock and ToBlock, both
+// inclusive, in chain order. A nil FromBlock starts at the genesis block, and a nil ToBlock
+// ends at the latest block.
+//
+// The range is queried in chunks, in parallel up to the configured concurrency. A chunk the
+// provider rejects for spanning too many blocks or matching too many logs is split in halves
+// until it is accepted.
+func (m *Manager) GetLogs(ctx context.Context, filter utils.EventFilter) ([]utils.Log, error) {
+	if m.client == nil {
+		return nil, utils.WrapError(utils.ErrClientNotStarted)
+	}
+
+	query, err := toFilterQuery(filter)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMFailedToFilterLogs, err)
+	}
+
+	var from, to uint64
+	if filter.FromBlock != nil {
+		from = *filter.FromBlock
+	}
+	if filter.ToBlock != nil {
+		to = *filter.ToBlock
+	} else {
+		to, err = m.client.BlockNumber(ctx)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrEVMFailedToFilterLogs, err)
+		}
+	}
+	if from > to {
+		return nil, nil
+	}
+
+	chunks, err := m.filterLogs(ctx, query, from, to)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMFailedToFilterLogs, err)
+	}
+
+	var logs []utils.Log
+	for _, log := range chunks {
+		converted, err := convertLog(log, m.network)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, converted)
+	}
+	return logs, nil
+}
+
+// filterLogs queries the logs between from and to in chunks, in parallel up to the
+// configured concurrency, and returns them in chain order.
+func (m *Manager) filterLogs(ctx context.Context, query ethereum.FilterQuery, from, to uint64) ([]types.Log, error) {
+	chunkSize := m.logQueryConfig.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = 2000
+	}
+	concurrency := m.logQueryConfig.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	// Every chunk writes to its own slot, so the results are kept in order
+	chunks := make([][]types.Log, (to-from)/chunkSize+1)
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for i := range chunks {
+		i := i
+		start := from + uint64(i)*chunkSize
+		end := min(start+chunkSize-1, to)
+		group.Go(func() error {
+			logs, err := m.filterLogsRange(groupCtx, query, start, end)
+			chunks[i] = logs
+			return err
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	var logs []types.Log
+	for _, chunk := range chunks {
+		logs = append(logs, chunk...)
+	}
+	return logs, nil
+}
+
+// filterLogsRange queries the logs between from and to, splitting the range in halves while
+// the provider rejects it.
+func (m *Manager) filterLogsRange(ctx context.Context, query ethereum.FilterQuery, from, to uint64) ([]types.Log, error) {
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(to)
+
+	logs, err := m.client.FilterLogs(ctx, query)
+	if err == nil {
+		return logs, nil
+	}
+	if from == to || !isLogRangeError(err) {
+		return nil, fmt.Errorf("blocks %d to %d: %w", from, to, err)
+	}
+
+	mid := from + (to-from)/2
+	left, err := m.filterLogsRange(ctx, query, from, mid)
+	if err != nil {
+		return nil, err
+	}
+	right, err := m.filterLogsRange(ctx, query, mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// isLogRangeError reports whether a log query was rejected for its range or result size. The
+// message decides, since Infura sends oversized queries with the -32005 code of its rate
+// limits; a throttled query matches no fragment and is not split.
+func isLogRangeError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range logRangeErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
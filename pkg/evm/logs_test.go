@@ -0,0 +1,164 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/mock/gomock"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestManager_GetLogs_Chunks tests that a large range is queried in parallel chunks and returned in order.
+// go test -v -cover ./pkg/evm -run TestManager_GetLogs_Chunks
+func TestManager_GetLogs_Chunks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithLogQueryConfig(evm.LogQueryConfig{ChunkSize: 1000, Concurrency: 3}))
+
+	var running, maxRunning atomic.Int32
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(9999), nil)
+	mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				highest := maxRunning.Load()
+				if current <= highest || maxRunning.CompareAndSwap(highest, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+
+			// Each chunk is aligned to the chunk size and returns a log at both ends
+			assert.Equal(t, uint64(999), query.ToBlock.Uint64()-query.FromBlock.Uint64())
+			return []types.Log{
+				newTestLog(query.FromBlock.Uint64(), 0),
+				newTestLog(query.ToBlock.Uint64(), 3),
+			}, nil
+		}).Times(10)
+
+	// Act
+	logs, err := manager.GetLogs(context.Background(), utils.EventFilter{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, logs, 20)
+	for i := 1; i < len(logs); i++ {
+		assert.Less(t, logs[i-1].BlockNumber, logs[i].BlockNumber)
+	}
+	assert.Equal(t, uint64(0), logs[0].BlockNumber)
+	assert.Equal(t, uint64(9999), logs[19].BlockNumber)
+	assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+}
+
+// TestManager_GetLogs_Split tests that chunks rejected by the provider are split in halves.
+// go test -v -cover ./pkg/evm -run TestManager_GetLogs_Split
+func TestManager_GetLogs_Split(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithLogQueryConfig(evm.LogQueryConfig{ChunkSize: 100}))
+
+	// The provider only accepts ranges of up to 30 blocks
+	var calls atomic.Int32
+	mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+			calls.Add(1)
+			from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+			if to-from >= 30 {
+				return nil, errors.New("query returned more than 10000 results")
+			}
+			return []types.Log{newTestLog(from, 0)}, nil
+		}).AnyTimes()
+
+	// Act
+	fromBlock, toBlock := uint64(100), uint64(199)
+	logs, err := manager.GetLogs(context.Background(), utils.EventFilter{FromBlock: &fromBlock, ToBlock: &toBlock})
+
+	// Assert: 100-199 -> 100-149, 150-199 -> 100-124, 125-149, 150-174, 175-199
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), calls.Load())
+	var blocks []uint64
+	for _, log := range logs {
+		blocks = append(blocks, log.BlockNumber)
+	}
+	assert.Equal(t, []uint64{100, 125, 150, 175}, blocks)
+}
+
+// TestManager_GetLogs_InfuraRange tests that Infura's oversized query error is split despite its limit exceeded code.
+// go test -v -cover ./pkg/evm -run TestManager_GetLogs_InfuraRange
+func TestManager_GetLogs_InfuraRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithLogQueryConfig(evm.LogQueryConfig{}))
+	fromBlock, toBlock := uint64(10), uint64(20)
+	filter := utils.EventFilter{FromBlock: &fromBlock, ToBlock: &toBlock}
+
+	gomock.InOrder(
+		mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).
+			Return(nil, testRPCCodeError{code: -32005, msg: "query returned more than 10000 results"}),
+		mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+				return []types.Log{newTestLog(query.FromBlock.Uint64(), 0)}, nil
+			}).Times(2),
+	)
+
+	// Act
+	logs, err := manager.GetLogs(context.Background(), filter)
+
+	// Assert: 10-20 -> 10-15, 16-20
+	assert.NoError(t, err)
+	var blocks []uint64
+	for _, log := range logs {
+		blocks = append(blocks, log.BlockNumber)
+	}
+	assert.Equal(t, []uint64{10, 16}, blocks)
+}
+
+// TestManager_GetLogs_Errors tests that failures that splitting cannot fix are reported.
+// go test -v -cover ./pkg/evm -run TestManager_GetLogs_Errors
+func TestManager_GetLogs_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl, evm.WithLogQueryConfig(evm.LogQueryConfig{}))
+	fromBlock, toBlock := uint64(10), uint64(20)
+	filter := utils.EventFilter{FromBlock: &fromBlock, ToBlock: &toBlock}
+
+	// Errors unrelated to the range are not retried
+	mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused")).Times(1)
+	_, err := manager.GetLogs(context.Background(), filter)
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToFilterLogs)
+	assert.ErrorContains(t, err, "connection refused")
+
+	// Rate limits are not mistaken for range errors, even with the code Infura uses for both
+	mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).
+		Return(nil, testRPCCodeError{code: -32005, msg: "daily request count exceeded, request rate limited"}).Times(1)
+	_, err = manager.GetLogs(context.Background(), filter)
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToFilterLogs)
+	assert.ErrorContains(t, err, "request rate limited")
+	mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).Return(nil, errors.New("limit exceeded")).Times(1)
+	_, err = manager.GetLogs(context.Background(), filter)
+	assert.ErrorContains(t, err, "limit exceeded")
+
+	// A single block with too many logs cannot be split further
+	mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).Return(nil, errors.New("Log response size exceeded")).AnyTimes()
+	_, err = manager.GetLogs(context.Background(), filter)
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToFilterLogs)
+	assert.ErrorContains(t, err, "Log response size exceeded")
+
+	// An empty range returns nothing
+	toBlock = 5
+	logs, err := manager.GetLogs(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Empty(t, logs)
+}
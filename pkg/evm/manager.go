@@ -13,6 +13,8 @@ import (
 	"github.com/mselser95/blockchain/pkg/utils"
 	"math/big"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Manager manages interactions with the EVM-compatible blockchain.
@@ -22,6 +24,25 @@ type Manager struct {
 	signer        signer.TransactionSigner
 	clientFactory ClientFactory
 	network       utils.Blockchain
+
+	gasMarginPercent uint64
+	autoPrepare      bool
+	nonceManager     *NonceManager
+	nonceAccounts    []utils.Address
+	feeOracle        *FeeOracle
+	feeOracleConfig  *FeeOracleConfig
+	feeSpeed         FeeSpeed
+	pollEvery        time.Duration
+	droppedAfter     time.Duration
+	tracker          *Tracker
+	logQueryConfig   LogQueryConfig
+	multicallConfig  MulticallConfig
+	eventRegistry    *EventRegistry
+	tokenResolver    *TokenResolver
+
+	replacementBumpPercent uint64
+	replacementsMu         sync.Mutex
+	replacements           map[common.Hash]common.Hash // Replaced transaction hash to replacement hash
 }
 
 // NewManager creates a new Manager instance.
@@ -30,13 +51,21 @@ func NewManager(
 	signer signer.TransactionSigner,
 	clientFactory ClientFactory,
 	network utils.Blockchain,
+	opts ...ManagerOption,
 ) manager.BlockchainManager {
-	return &Manager{
+	m := &Manager{
 		url:           url,
 		signer:        signer,
 		clientFactory: clientFactory,
 		network:       network,
+		feeSpeed:      FeeSpeedStandard,
+		eventRegistry: NewEventRegistry(),
+		tokenResolver: NewTokenResolver(nil),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 // Start establishes a connection to the EVM-compatible blockchain.
@@ -52,6 +81,24 @@ func (m *Manager) Start(ctx context.Context) error {
 			return fmt.Errorf("unable to connect to EVM client at %s: %w", m.url, err)
 		}
 		m.client = c
+
+		for _, account := range m.nonceAccounts {
+			if err := m.nonceManager.Sync(ctx, c, common.HexToAddress(account.String())); err != nil {
+				m.client = nil
+				c.Close()
+				return fmt.Errorf("unable to sync nonce of %s: %w", account.String(), err)
+			}
+		}
+
+		feeOracleConfig := DefaultFeeOracleConfig(m.network)
+		if m.feeOracleConfig != nil {
+			feeOracleConfig = feeOracleConfig.merge(*m.feeOracleConfig)
+		}
+		m.feeOracle = NewFeeOracle(c, feeOracleConfig)
+
+		if m.tracker != nil {
+			m.tracker.start(c)
+		}
 		return nil
 	}
 	return utils.WrapError(utils.ErrAlreadyStarted)
@@ -60,6 +107,9 @@ func (m *Manager) Start(ctx context.Context) error {
 // Stop stops the Manager and cleans up resources.
 func (m *Manager) Stop(ctx context.Context) error {
 	if m.client != nil {
+		if m.tracker != nil {
+			m.tracker.stop()
+		}
 		m.client.Close()
 		return nil
 	}
@@ -77,6 +127,10 @@ func (m *Manager) GetBalance(ctx context.Context, address utils.Address, token u
 		return m.getNativeBalance(ctx, address)
 	case utils.ERC20:
 		return m.getERC20Balance(ctx, address, token)
+	case utils.ERC721:
+		return m.getERC721Balance(ctx, address, token)
+	case utils.ERC1155:
+		return m.getERC1155Balance(ctx, address, token)
 	default:
 		errMsg := fmt.Sprintf("%s: %v", utils.ErrUnsupportedTokenType, token.Type)
 		return nil, utils.WrapError(errMsg)
@@ -84,53 +138,130 @@ func (m *Manager) GetBalance(ctx context.Context, address utils.Address, token u
 }
 
 // ReadCall performs a read-only call to a contract on the EVM blockchain.
+//
+// The call is described by the transaction payload:
+//   - "abi": the contract ABI, as a JSON string or an abi.ABI
+//   - "method": the name of the method to call
+//   - "args": the method arguments as []interface{} (optional)
+//   - "blockNumber" (*big.Int) or "blockHash" (common.Hash): the block to call at (optional)
+//   - "pending" (bool): call against the pending state instead (optional)
+//
+// When an ABI is given, the decoded outputs are returned as []interface{}. Without an ABI,
+// the raw "data" payload is sent as-is and the raw output bytes are returned.
 func (m *Manager) ReadCall(ctx context.Context, tx utils.Transaction) (interface{}, error) {
-	return nil, utils.WrapError(utils.ErrNotImplemented)
+	if m.client == nil {
+		return nil, utils.WrapError(utils.ErrClientNotStarted)
+	}
+
+	msg, err := newCallMsg(tx)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	payload := tx.Payload()
+	parsedABI, method, err := callABI(payload)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	if parsedABI != nil {
+		args, ok := payload["args"].([]interface{})
+		if _, exists := payload["args"]; exists && !ok {
+			return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("payload 'args' must be []interface{}"))
+		}
+		msg.Data, err = parsedABI.Pack(method, args...)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+		}
+	}
+
+	output, err := m.callContract(ctx, msg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedABI == nil {
+		return output, nil
+	}
+
+	values, err := parsedABI.Unpack(method, output)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMFailedToUnpackOutput, err)
+	}
+	return values, nil
 }
 
 // EstimateGas estimates the gas required to execute a transaction on the EVM blockchain.
+// The configured gas margin is added on top of the node's estimate. If the execution
+// would revert, the returned error contains ErrEVMExecutionReverted and the decoded reason.
 func (m *Manager) EstimateGas(ctx context.Context, tx utils.Transaction) (*big.Int, error) {
-	return nil, utils.WrapError(utils.ErrNotImplemented)
+	if m.client == nil {
+		return nil, utils.WrapError(utils.ErrClientNotStarted)
+	}
+
+	msg, err := newCallMsg(tx)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	// Let the node pick the gas cap for the estimation
+	msg.Gas = 0
+
+	gas, err := m.client.EstimateGas(ctx, msg)
+	if err != nil {
+		return nil, wrapCallError(utils.ErrEVMFailedToEstimateGas, err)
+	}
+
+	estimate := new(big.Int).SetUint64(gas)
+	if m.gasMarginPercent > 0 {
+		margin := new(big.Int).Mul(estimate, new(big.Int).SetUint64(m.gasMarginPercent))
+		estimate.Add(estimate, margin.Div(margin, big.NewInt(100)))
+	}
+
+	return estimate, nil
 }
 
 // SendTransaction sends a transaction to the EVM blockchain.
 func (m *Manager) SendTransaction(ctx context.Context, tx utils.Transaction) (string, error) {
+	return m.sendTransaction(ctx, tx, m.autoPrepare)
+}
+
+// sendTransaction signs and broadcasts a transaction, preparing it first when requested.
+func (m *Manager) sendTransaction(ctx context.Context, tx utils.Transaction, prepare bool) (string, error) {
 	if m.client == nil {
 		return "", utils.WrapError(utils.ErrClientNotStarted)
 	}
-
-	// Sign the transaction using the configured signer
-	signedTx, err := m.signer.SignTransaction(tx)
-	if err != nil {
-		return "", utils.WrapError(utils.ErrEVMFailedToSignTransaction, err)
-	}
-	if signedTx == nil {
+	if tx == nil {
 		return "", utils.WrapError(utils.ErrEVMInvalidTransaction)
 	}
 
-	// Deserialize the signed transaction from the transaction payload
-	ethTx, ok := signedTx.Payload()["signedTransaction"].(*types.Transaction)
-	if !ok || ethTx == nil {
-		return "", utils.WrapError(utils.ErrEVMInvalidTransaction)
+	// Fill in the missing chain ID, fees, gas limit and nonce when requested. The nonce is
+	// reserved only once everything else is prepared, and released again if signing or
+	// the broadcast fails.
+	_, hadNonce := tx.Payload()["nonce"]
+	if prepare {
+		if err := m.PrepareTransaction(ctx, tx); err != nil {
+			return "", err
+		}
 	}
+	_, hasNonce := tx.Payload()["nonce"]
+	reserved := m.nonceManager != nil && !hadNonce && hasNonce
 
-	// Send the signed transaction to the Ethereum network
-	err = m.client.SendTransaction(ctx, ethTx)
+	ethTx, err := m.signTransaction(tx)
 	if err != nil {
-		// Handle known errors based on their messages
-		errMsg := err.Error()
-		switch {
-		case strings.Contains(errMsg, "insufficient funds"):
-			return "", utils.WrapError(utils.ErrEVMInsufficientFunds, err)
-		case strings.Contains(errMsg, "exceeds block gas limit"):
-			return "", utils.WrapError(utils.ErrEVMMaxGasCapExceeded, err)
-		case strings.Contains(errMsg, "replacement transaction underpriced"):
-			return "", utils.WrapError(utils.ErrEVMReplacementUnderpriced, err)
-		case strings.Contains(errMsg, "nonce too low"):
-			return "", utils.WrapError(utils.ErrEVMNonceTooLow, err)
-		default:
-			return "", utils.WrapError(utils.ErrEVMFailedToSendTransaction, err)
+		if reserved {
+			m.releaseNonce(tx)
 		}
+		return "", err
+	}
+
+	if err := m.broadcast(ctx, ethTx); err != nil {
+		m.recoverNonce(ctx, tx, err, reserved)
+		return "", err
+	}
+
+	if m.tracker != nil {
+		m.tracker.track(tx, ethTx)
 	}
 
 	// Return the transaction hash
@@ -174,7 +305,6 @@ func (m *Manager) GetTransactionDetails(ctx context.Context, txID string) (*util
 
 	// Retrieve logs from the transaction receipt
 	var logs []utils.Log
-	var events []utils.Event
 	for _, log := range receipt.Logs {
 		// Convert topics to strings
 		topics := topicsToStrings(log.Topics)
@@ -193,14 +323,6 @@ func (m *Manager) GetTransactionDetails(ctx context.Context, txID string) (*util
 			TxHash:      hash,
 			Index:       log.Index,
 		})
-
-		// Example: Parse the event name from the first topic and add abstract events
-		if len(topics) > 0 {
-			events = append(events, utils.Event{
-				Name:   topics[0],                    // Assuming the first topic is the event name (this may vary)
-				Params: make(map[string]interface{}), // Add logic to populate parameters as needed
-			})
-		}
 	}
 
 	// Create the TransactionDetails object
@@ -214,27 +336,83 @@ func (m *Manager) GetTransactionDetails(ctx context.Context, txID string) (*util
 		return nil, utils.WrapError(utils.ErrEVMInvalidAddress, err)
 	}
 
-	to, err := NewAddress(tx.To().Hex(), m.network)
-	if err != nil {
-		return nil, utils.WrapError(utils.ErrEVMInvalidAddress, err)
+	// Contract deployments have no recipient, but the receipt has the created contract
+	var to, contractAddress utils.Address
+	if tx.To() != nil {
+		to, err = NewAddress(tx.To().Hex(), m.network)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrEVMInvalidAddress, err)
+		}
+	} else if receipt.ContractAddress != (common.Address{}) {
+		contractAddress = &Address{address: receipt.ContractAddress, network: m.network}
 	}
 
+	events, eventsByName := m.decodeEvents(receipt.Logs)
 	details := &utils.TransactionDetails{
-		Hash:        hash,
-		Status:      status,
-		BlockNumber: receipt.BlockNumber.Uint64(),
-		From:        from,
-		To:          to,
-		Amount:      tx.Value(),
-		Fee:         new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed)),
-		Logs:        logs,
-		Events:      convertEventsToMap(events), // Abstract events added here
+		Hash:            hash,
+		Status:          status,
+		BlockNumber:     receipt.BlockNumber.Uint64(),
+		From:            from,
+		To:              to,
+		ContractAddress: contractAddress,
+		Amount:          tx.Value(),
+		Fee:             transactionFee(tx, receipt),
+		BlobGasUsed:     receipt.BlobGasUsed,
+		BlobFee:         blobFee(receipt),
+		Logs:            logs,
+		Events:          eventsByName,
+		DecodedEvents:   events,
 	}
 
 	return details, nil
 }
 
 // Internal functions:
+
+// signTransaction signs the transaction with the configured signer and returns the signed EVM transaction.
+func (m *Manager) signTransaction(tx utils.Transaction) (*types.Transaction, error) {
+	// Sign the transaction using the configured signer
+	signedTx, err := m.signer.SignTransaction(tx)
+	if err != nil {
+		return nil, utils.WrapError(utils.ErrEVMFailedToSignTransaction, err)
+	}
+	if signedTx == nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction)
+	}
+
+	// Deserialize the signed transaction from the transaction payload
+	ethTx, ok := signedTx.Payload()["signedTransaction"].(*types.Transaction)
+	if !ok || ethTx == nil {
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction)
+	}
+
+	return ethTx, nil
+}
+
+// broadcast sends a signed transaction to the network, mapping known node errors.
+func (m *Manager) broadcast(ctx context.Context, ethTx *types.Transaction) error {
+	// Send the signed transaction to the Ethereum network
+	err := m.client.SendTransaction(ctx, ethTx)
+	if err != nil {
+		// Handle known errors based on their messages
+		errMsg := err.Error()
+		switch {
+		case strings.Contains(errMsg, "insufficient funds"):
+			return utils.WrapError(utils.ErrEVMInsufficientFunds, err)
+		case strings.Contains(errMsg, "exceeds block gas limit"):
+			return utils.WrapError(utils.ErrEVMMaxGasCapExceeded, err)
+		case strings.Contains(errMsg, "replacement transaction underpriced"):
+			return utils.WrapError(utils.ErrEVMReplacementUnderpriced, err)
+		case strings.Contains(errMsg, "nonce too low"):
+			return utils.WrapError(utils.ErrEVMNonceTooLow, err)
+		default:
+			return utils.WrapError(utils.ErrEVMFailedToSendTransaction, err)
+		}
+	}
+
+	return nil
+}
+
 func (m *Manager) getNativeBalance(ctx context.Context, address utils.Address) (*big.Int, error) {
 	balance, err := m.client.BalanceAt(ctx, common.HexToAddress(address.String()), nil)
 	if err != nil {
@@ -286,6 +464,103 @@ func (m *Manager) getERC20Balance(ctx context.Context, address utils.Address, to
 	return balance, nil
 }
 
+// callContract executes the call at the block selected by the payload.
+func (m *Manager) callContract(ctx context.Context, msg ethereum.CallMsg, payload map[string]interface{}) ([]byte, error) {
+	blockNumber, hasNumber := payload["blockNumber"]
+	blockHash, hasHash := payload["blockHash"]
+
+	isPending := false
+	if pending, ok := payload["pending"]; ok {
+		if isPending, ok = pending.(bool); !ok {
+			return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("payload 'pending' must be bool"))
+		}
+	}
+	if (hasNumber && hasHash) || (isPending && (hasNumber || hasHash)) {
+		err := errors.New("only one of 'blockNumber', 'blockHash' and 'pending' can be set")
+		return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	var output []byte
+	var err error
+	switch {
+	case isPending:
+		output, err = m.client.PendingCallContract(ctx, msg)
+	case hasHash:
+		hash, ok := blockHash.(common.Hash)
+		if !ok {
+			return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("payload 'blockHash' must be common.Hash"))
+		}
+		output, err = m.client.CallContractAtHash(ctx, msg, hash)
+	case hasNumber:
+		number, ok := blockNumber.(*big.Int)
+		if !ok {
+			return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("payload 'blockNumber' must be *big.Int"))
+		}
+		output, err = m.client.CallContract(ctx, msg, number)
+	default:
+		output, err = m.client.CallContract(ctx, msg, nil)
+	}
+	if err != nil {
+		return nil, wrapCallError(utils.ErrEVMFailedToCallContract, err)
+	}
+
+	return output, nil
+}
+
+// callABI returns the parsed ABI and method name from the payload, or a nil ABI when
+// the payload does not describe an ABI call.
+func callABI(payload map[string]interface{}) (*abi.ABI, string, error) {
+	rawABI, ok := payload["abi"]
+	if !ok {
+		return nil, "", nil
+	}
+
+	var parsedABI abi.ABI
+	switch a := rawABI.(type) {
+	case string:
+		var err error
+		parsedABI, err = abi.JSON(strings.NewReader(a))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse ABI: %w", err)
+		}
+	case abi.ABI:
+		parsedABI = a
+	case *abi.ABI:
+		parsedABI = *a
+	default:
+		return nil, "", errors.New("payload 'abi' must be a JSON string or abi.ABI")
+	}
+
+	method, ok := payload["method"].(string)
+	if !ok || method == "" {
+		return nil, "", errors.New("payload 'method' must be a non-empty string")
+	}
+	if _, ok := parsedABI.Methods[method]; !ok {
+		return nil, "", fmt.Errorf("method %q not found in ABI", method)
+	}
+
+	return &parsedABI, method, nil
+}
+
+// transactionFee computes the fee paid by a transaction. The effective gas price reported
+// in the receipt is used when available, since dynamic fee transactions pay less than
+// their fee cap.
+func transactionFee(tx *types.Transaction, receipt *types.Receipt) *big.Int {
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+	return new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+}
+
+// blobFee computes the fee paid for the blob gas of a transaction, or nil if it carried no blobs.
+func blobFee(receipt *types.Receipt) *big.Int {
+	if receipt.BlobGasUsed == 0 || receipt.BlobGasPrice == nil {
+		return nil
+	}
+	return new(big.Int).Mul(receipt.BlobGasPrice, new(big.Int).SetUint64(receipt.BlobGasUsed))
+}
+
 // Helper function to convert topics to strings
 func topicsToStrings(topics []common.Hash) []string {
 	var result []string
@@ -294,12 +569,3 @@ func topicsToStrings(topics []common.Hash) []string {
 	}
 	return result
 }
-
-// Helper function to convert events to a map
-func convertEventsToMap(events []utils.Event) map[string]interface{} {
-	eventMap := make(map[string]interface{})
-	for _, event := range events {
-		eventMap[event.Name] = event.Params
-	}
-	return eventMap
-}
@@ -2,11 +2,17 @@ package evm_test
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -45,6 +51,31 @@ func generateSignedTransaction(t *testing.T, toAddress common.Address, chainID *
 	return signedTx, fromAddress
 }
 
+// testKey returns the key the managers of a test sign with, derived from the test name.
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	privateKey, err := crypto.ToECDSA(crypto.Keccak256([]byte(t.Name())))
+	assert.NoError(t, err)
+	return privateKey
+}
+
+// newTestManager starts a Manager with the given options on top of a mock client. It signs
+// with the test's key, whose address is returned as the sender.
+func newTestManager(t *testing.T, ctrl *gomock.Controller, opts ...evm.ManagerOption) (*evm.Manager, *mock_evm.MockClientInterface, utils.Address) {
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	privateKey := testKey(t)
+	signer, err := evm.NewPrivateKeySigner(fmt.Sprintf("%x", crypto.FromECDSA(privateKey)))
+	assert.NoError(t, err)
+	from, err := evm.NewAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	manager := evm.NewManager("http://localhost:8545", signer, mockClientFactory, utils.Ethereum, opts...).(*evm.Manager)
+	assert.NoError(t, manager.Start(context.Background()))
+	return manager, mockClient, from
+}
+
 // To run all tests in this file from the root directory with coverage and verbosity:
 // go test -cover ./pkg/evm
 
@@ -602,3 +633,371 @@ func TestManager_GetTransactionDetails_ReceiptNotFound(t *testing.T) {
 	assert.Nil(t, details)
 	assert.ErrorContains(t, err, utils.ErrEVMFailedToRetrieveTransaction)
 }
+
+// rpcDataError mimics a JSON-RPC error carrying revert data.
+type rpcDataError struct {
+	msg  string
+	data interface{}
+}
+
+func (e *rpcDataError) Error() string          { return e.msg }
+func (e *rpcDataError) ErrorData() interface{} { return e.data }
+
+// encodeRevertReason ABI-encodes a reason the way Solidity's Error(string) does.
+func encodeRevertReason(t *testing.T, reason string) string {
+	stringType, err := abi.NewType("string", "", nil)
+	assert.NoError(t, err)
+	packed, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	assert.NoError(t, err)
+	return hexutil.Encode(append(crypto.Keccak256([]byte("Error(string)"))[:4], packed...))
+}
+
+// TestManager_EstimateGas_Success tests that EstimateGas builds the call message and applies the gas margin.
+// go test -v -cover ./pkg/evm -run TestManager_EstimateGas_Success
+func TestManager_EstimateGas_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum, evm.WithGasMargin(20))
+	manager.Start(context.Background())
+
+	from := generateRandomAddress()
+	to := generateRandomAddress()
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, from, to, big.NewInt(1000), &txType, nil, nil, nil,
+		0, big.NewInt(50), nil, 0, []byte{0x1, 0x2})
+
+	toAddress := common.HexToAddress(to.String())
+	mockClient.EXPECT().EstimateGas(gomock.Any(), ethereum.CallMsg{
+		From:     common.HexToAddress(from.String()),
+		To:       &toAddress,
+		Value:    big.NewInt(1000),
+		GasPrice: big.NewInt(50),
+		Data:     []byte{0x1, 0x2},
+	}).Return(uint64(50000), nil)
+
+	// Act
+	gas, err := manager.EstimateGas(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(60000), gas)
+}
+
+// TestManager_EstimateGas_Reverted tests that EstimateGas decodes the revert reason.
+// go test -v -cover ./pkg/evm -run TestManager_EstimateGas_Reverted
+func TestManager_EstimateGas_Reverted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(0), &txType,
+		nil, nil, nil, 0, nil, nil, 0, nil)
+
+	revertErr := &rpcDataError{
+		msg:  "execution reverted: ERC20: transfer amount exceeds balance",
+		data: encodeRevertReason(t, "ERC20: transfer amount exceeds balance"),
+	}
+	mockClient.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(0), revertErr)
+
+	// Act
+	gas, err := manager.EstimateGas(context.Background(), tx)
+
+	// Assert
+	assert.Nil(t, gas)
+	assert.ErrorContains(t, err, utils.ErrEVMExecutionReverted+": ERC20: transfer amount exceeds balance")
+	assert.ErrorIs(t, err, revertErr)
+}
+
+// TestManager_EstimateGas_RPCError tests that EstimateGas distinguishes RPC failures from reverts.
+// go test -v -cover ./pkg/evm -run TestManager_EstimateGas_RPCError
+func TestManager_EstimateGas_RPCError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	txType := utils.Transfer
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(1), &txType,
+		nil, nil, nil, 0, nil, nil, 0, nil)
+
+	mockClient.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(0), errors.New("connection refused"))
+
+	// Act
+	gas, err := manager.EstimateGas(context.Background(), tx)
+
+	// Assert
+	assert.Nil(t, gas)
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToEstimateGas)
+	assert.False(t, utils.IsError(err, utils.ErrEVMExecutionReverted))
+}
+
+// TestManager_EstimateGas_ClientNotStarted tests the EstimateGas method when the client is not started.
+// go test -v -cover ./pkg/evm -run TestManager_EstimateGas_ClientNotStarted
+func TestManager_EstimateGas_ClientNotStarted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+
+	_, err := manager.EstimateGas(context.Background(), &evm.BaseTransaction{})
+	assert.ErrorContains(t, err, utils.ErrClientNotStarted)
+}
+
+const testReservesAbi = `[{
+	"inputs":[{"name":"pair","type":"address"}],
+	"name":"getReserves",
+	"outputs":[{"name":"reserve0","type":"uint112"},{"name":"reserve1","type":"uint112"}],
+	"stateMutability":"view",
+	"type":"function"
+}]`
+
+// TestManager_ReadCall_Success tests that ReadCall packs the arguments and decodes the outputs.
+// go test -v -cover ./pkg/evm -run TestManager_ReadCall_Success
+func TestManager_ReadCall_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	parsedABI, err := abi.JSON(strings.NewReader(testReservesAbi))
+	assert.NoError(t, err)
+
+	pair := common.HexToAddress(generateRandomAddress().String())
+	contract := generateRandomAddress()
+	expectedInput, err := parsedABI.Pack("getReserves", pair)
+	assert.NoError(t, err)
+	output, err := parsedABI.Methods["getReserves"].Outputs.Pack(big.NewInt(100), big.NewInt(200))
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(func(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+			assert.Equal(t, contract.String(), msg.To.Hex())
+			assert.Equal(t, expectedInput, msg.Data)
+			return output, nil
+		})
+
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, nil, contract, nil, &txType, nil, nil, nil, 0, nil, nil, 0, nil)
+	tx.SetPayload("abi", testReservesAbi)
+	tx.SetPayload("method", "getReserves")
+	tx.SetPayload("args", []interface{}{pair})
+
+	// Act
+	result, err := manager.ReadCall(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	values, ok := result.([]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{big.NewInt(100), big.NewInt(200)}, values)
+}
+
+// TestManager_ReadCall_BlockSelection tests that ReadCall honours the block hash and pending options.
+// go test -v -cover ./pkg/evm -run TestManager_ReadCall_BlockSelection
+func TestManager_ReadCall_BlockSelection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	blockHash := common.HexToHash(generateRandomHash().String())
+	mockClient.EXPECT().CallContractAtHash(gomock.Any(), gomock.Any(), blockHash).Return([]byte{0x1}, nil)
+	mockClient.EXPECT().PendingCallContract(gomock.Any(), gomock.Any()).Return([]byte{0x2}, nil)
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), big.NewInt(42)).Return([]byte{0x3}, nil)
+
+	txType := utils.ContractCall
+	newCall := func(key string, value interface{}) utils.Transaction {
+		tx := evm.NewTransaction(nil, nil, generateRandomAddress(), nil, &txType, nil, nil, nil, 0, nil, nil, 0, []byte{0xaa})
+		tx.SetPayload(key, value)
+		return tx
+	}
+
+	// Act & Assert
+	result, err := manager.ReadCall(context.Background(), newCall("blockHash", blockHash))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x1}, result)
+
+	result, err = manager.ReadCall(context.Background(), newCall("pending", true))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x2}, result)
+
+	result, err = manager.ReadCall(context.Background(), newCall("blockNumber", big.NewInt(42)))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x3}, result)
+
+	conflicting := newCall("blockNumber", big.NewInt(42))
+	conflicting.SetPayload("blockHash", blockHash)
+	_, err = manager.ReadCall(context.Background(), conflicting)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidTransaction)
+}
+
+// TestManager_ReadCall_Reverted tests that ReadCall decodes the revert reason.
+// go test -v -cover ./pkg/evm -run TestManager_ReadCall_Reverted
+func TestManager_ReadCall_Reverted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	revertErr := &rpcDataError{msg: "execution reverted", data: encodeRevertReason(t, "pair not found")}
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(nil, revertErr)
+
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, nil, generateRandomAddress(), nil, &txType, nil, nil, nil, 0, nil, nil, 0, nil)
+	tx.SetPayload("abi", testReservesAbi)
+	tx.SetPayload("method", "getReserves")
+	tx.SetPayload("args", []interface{}{common.HexToAddress(generateRandomAddress().String())})
+
+	// Act
+	result, err := manager.ReadCall(context.Background(), tx)
+
+	// Assert
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, utils.ErrEVMExecutionReverted+": pair not found")
+}
+
+// TestManager_ReadCall_UnknownMethod tests that ReadCall rejects methods missing from the ABI.
+// go test -v -cover ./pkg/evm -run TestManager_ReadCall_UnknownMethod
+func TestManager_ReadCall_UnknownMethod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	manager.Start(context.Background())
+
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, nil, generateRandomAddress(), nil, &txType, nil, nil, nil, 0, nil, nil, 0, nil)
+	tx.SetPayload("abi", testReservesAbi)
+	tx.SetPayload("method", "getPrice")
+
+	// Act
+	result, err := manager.ReadCall(context.Background(), tx)
+
+	// Assert
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidTransaction)
+}
+
+// TestManager_GetTransactionDetails_DynamicFee tests that the fee is computed from the receipt's effective gas price.
+// go test -v -cover ./pkg/evm -run TestManager_GetTransactionDetails_DynamicFee
+func TestManager_GetTransactionDetails_DynamicFee(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+	manager.Start(context.Background())
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	to := common.HexToAddress(generateRandomAddress().String())
+	tx, err := types.SignNewTx(privateKey, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     3,
+		GasTipCap: big.NewInt(2),
+		GasFeeCap: big.NewInt(100),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1000),
+	})
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(&types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		BlockNumber:       big.NewInt(10),
+		GasUsed:           21000,
+		EffectiveGasPrice: big.NewInt(42),
+	}, nil)
+
+	// Act
+	details, err := manager.GetTransactionDetails(context.Background(), tx.Hash().Hex())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), details.From.String())
+	assert.Equal(t, big.NewInt(882000), details.Fee) // EffectiveGasPrice (42) * GasUsed (21000)
+}
+
+// TestManager_GetTransactionDetails_BlobGas tests that blob gas usage and the blob fee are reported.
+// go test -v -cover ./pkg/evm -run TestManager_GetTransactionDetails_BlobGas
+func TestManager_GetTransactionDetails_BlobGas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+	manager.Start(context.Background())
+
+	tx, _ := generateSignedTransaction(t, common.HexToAddress(generateRandomAddress().String()), big.NewInt(1))
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), tx.Hash()).Return(&types.Receipt{
+		Status:       types.ReceiptStatusSuccessful,
+		BlockNumber:  big.NewInt(10),
+		GasUsed:      21000,
+		BlobGasUsed:  131072,
+		BlobGasPrice: big.NewInt(3),
+	}, nil)
+
+	// Act
+	details, err := manager.GetTransactionDetails(context.Background(), tx.Hash().Hex())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(131072), details.BlobGasUsed)
+	assert.Equal(t, big.NewInt(393216), details.BlobFee) // BlobGasPrice (3) * BlobGasUsed (131072)
+}
@@ -0,0 +1,265 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// Multicall3Address is the address Multicall3 is deployed at on most EVM chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// MulticallConfig configures how GetBalances aggregates calls through Multicall3.
+type MulticallConfig struct {
+	// Address is the Multicall3 contract address, Multicall3Address by default.
+	Address common.Address
+	// BatchSize is the maximum number of calls aggregated in one eth_call, 500 by default.
+	BatchSize int
+}
+
+// multicallSizeErrors are fragments of the errors providers return when an aggregated call
+// is too large to execute or to return.
+var multicallSizeErrors = []string{
+	"out of gas",
+	"gas required exceeds",
+	"gas limit",
+	"response size",
+	"too large",
+	"execution aborted",
+}
+
+// multicallCall is a call aggregated through Multicall3.
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// multicallResult is the result of a call aggregated through Multicall3.
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// balanceCall is a balance read of one address for one token.
+type balanceCall struct {
+	address int
+	token   int
+	call    multicallCall
+	decode  func([]byte) (*big.Int, error)
+}
+
+// GetBalances returns the balance of every address for every token, indexed by address and
+// then by token, aggregating the reads through the Multicall3 contract. Native, ERC20,
+// ERC721 and ERC1155 tokens are supported, with the same semantics as GetBalance.
+//
+// A read that fails, for example because a token contract reverts, leaves a nil balance
+// without failing the others. Calls are sent in batches of the configured size, and a batch
+// the provider rejects for its size is split in halves until it is accepted.
+func (m *Manager) GetBalances(ctx context.Context, addresses []utils.Address, tokens []utils.Token) ([][]*big.Int, error) {
+	if m.client == nil {
+		return nil, utils.WrapError(utils.ErrClientNotStarted)
+	}
+
+	calls, err := m.balanceCalls(addresses, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([][]*big.Int, len(addresses))
+	for i := range balances {
+		balances[i] = make([]*big.Int, len(tokens))
+	}
+
+	batchSize := m.multicallConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	for start := 0; start < len(calls); start += batchSize {
+		batch := calls[start:min(start+batchSize, len(calls))]
+
+		aggregated := make([]multicallCall, len(batch))
+		for i, call := range batch {
+			aggregated[i] = call.call
+		}
+		results, err := m.aggregate(ctx, aggregated)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrEVMFailedToAggregateCalls, err)
+		}
+
+		for i, result := range results {
+			if !result.Success {
+				continue
+			}
+			if balance, err := batch[i].decode(result.ReturnData); err == nil {
+				balances[batch[i].address][batch[i].token] = balance
+			}
+		}
+	}
+
+	return balances, nil
+}
+
+// balanceCalls builds the calls reading the balance of every address for every token.
+func (m *Manager) balanceCalls(addresses []utils.Address, tokens []utils.Token) ([]balanceCall, error) {
+	multicallABI, err := abi.JSON(strings.NewReader(multicall3Abi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Multicall3 ABI: %w", err)
+	}
+	erc20ABI, err := abi.JSON(strings.NewReader(erc20Abi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC20 ABI: %w", err)
+	}
+	erc721ABI, err := abi.JSON(strings.NewReader(erc721Abi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC721 ABI: %w", err)
+	}
+	erc1155ABI, err := abi.JSON(strings.NewReader(erc1155Abi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC1155 ABI: %w", err)
+	}
+
+	owners := make([]common.Address, len(addresses))
+	for i, address := range addresses {
+		if address == nil || !common.IsHexAddress(address.String()) {
+			return nil, utils.WrapError(utils.ErrEVMInvalidAddress, fmt.Errorf("invalid address at index %d", i))
+		}
+		owners[i] = common.HexToAddress(address.String())
+	}
+
+	calls := make([]balanceCall, 0, len(addresses)*len(tokens))
+	for j, token := range tokens {
+		for i, owner := range owners {
+			call := balanceCall{address: i, token: j, decode: decodeUint256}
+
+			var contract common.Address
+			var data []byte
+			switch token.Type {
+			case utils.Native:
+				contract = m.multicallAddress()
+				data, err = multicallABI.Pack("getEthBalance", owner)
+			case utils.ERC20:
+				if contract, err = tokenAddress(token, utils.ERC20); err == nil {
+					data, err = erc20ABI.Pack("balanceOf", owner)
+				}
+			case utils.ERC721:
+				if contract, err = tokenAddress(token, utils.ERC721); err != nil {
+					break
+				}
+				if token.ID == nil {
+					data, err = erc721ABI.Pack("balanceOf", owner)
+					break
+				}
+				data, err = erc721ABI.Pack("ownerOf", token.ID)
+				call.decode = ownershipDecoder(owner)
+			case utils.ERC1155:
+				if token.ID == nil {
+					return nil, utils.WrapError(utils.ErrEVMInvalidToken, fmt.Errorf("missing token ID at index %d", j))
+				}
+				if contract, err = tokenAddress(token, utils.ERC1155); err == nil {
+					data, err = erc1155ABI.Pack("balanceOf", owner, token.ID)
+				}
+			default:
+				return nil, utils.WrapError(fmt.Sprintf("%s: %v", utils.ErrUnsupportedTokenType, token.Type))
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			call.call = multicallCall{Target: contract, AllowFailure: true, CallData: data}
+			calls = append(calls, call)
+		}
+	}
+	return calls, nil
+}
+
+// aggregate executes calls in a single eth_call to Multicall3's aggregate3, splitting them
+// in halves while the provider rejects the call for its size.
+func (m *Manager) aggregate(ctx context.Context, calls []multicallCall) ([]multicallResult, error) {
+	multicallABI, err := abi.JSON(strings.NewReader(multicall3Abi))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Multicall3 ABI: %w", err)
+	}
+	data, err := multicallABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, err
+	}
+
+	multicall := m.multicallAddress()
+	output, err := m.client.CallContract(ctx, ethereum.CallMsg{To: &multicall, Data: data}, nil)
+	if err != nil {
+		if len(calls) == 1 || !isMulticallSizeError(err) {
+			return nil, wrapCallError(utils.ErrEVMFailedToCallContract, err)
+		}
+
+		mid := len(calls) / 2
+		left, err := m.aggregate(ctx, calls[:mid])
+		if err != nil {
+			return nil, err
+		}
+		right, err := m.aggregate(ctx, calls[mid:])
+		if err != nil {
+			return nil, err
+		}
+		return append(left, right...), nil
+	}
+
+	var results []multicallResult
+	if err := multicallABI.UnpackIntoInterface(&results, "aggregate3", output); err != nil {
+		return nil, utils.WrapError(utils.ErrEVMFailedToUnpackOutput, err)
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("expected %d results, got %d", len(calls), len(results))
+	}
+	return results, nil
+}
+
+// multicallAddress returns the address of the configured Multicall3 contract.
+func (m *Manager) multicallAddress() common.Address {
+	if m.multicallConfig.Address != (common.Address{}) {
+		return m.multicallConfig.Address
+	}
+	return Multicall3Address
+}
+
+// isMulticallSizeError reports whether an aggregated call was rejected for its size, going by
+// the message whatever the error code. Throttled calls match none of the fragments, so they
+// fail instead of being split into more calls.
+func isMulticallSizeError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range multicallSizeErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeUint256 decodes a single uint256 return value.
+func decodeUint256(data []byte) (*big.Int, error) {
+	if len(data) != 32 {
+		return nil, errors.New("invalid uint256 return data")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// ownershipDecoder decodes an ownerOf return value into a balance of 1 when the token is
+// owned by the owner and 0 otherwise.
+func ownershipDecoder(owner common.Address) func([]byte) (*big.Int, error) {
+	return func(data []byte) (*big.Int, error) {
+		if len(data) != 32 {
+			return nil, errors.New("invalid address return data")
+		}
+		if common.BytesToAddress(data) == owner {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	}
+}
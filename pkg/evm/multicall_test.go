@@ -0,0 +1,202 @@
+package evm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMulticall3Abi = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+// fakeMulticall executes aggregate3 calls against in-memory balances, the way Multicall3
+// would against deployed contracts. Calls to unknown targets fail.
+type fakeMulticall struct {
+	t        *testing.T
+	native   map[common.Address]*big.Int
+	tokens   map[common.Address]map[common.Address]*big.Int
+	batches  []int
+	maxCalls int // Aggregations with more calls run out of gas
+}
+
+// call answers an eth_call to Multicall3.
+func (f *fakeMulticall) call(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	assert.Equal(f.t, evm.Multicall3Address, *msg.To)
+
+	parsed, err := abi.JSON(strings.NewReader(testMulticall3Abi))
+	assert.NoError(f.t, err)
+	method := parsed.Methods["aggregate3"]
+	assert.Equal(f.t, method.ID, msg.Data[:4])
+
+	args, err := method.Inputs.Unpack(msg.Data[4:])
+	assert.NoError(f.t, err)
+	calls := args[0].([]struct {
+		Target       common.Address `json:"target"`
+		AllowFailure bool           `json:"allowFailure"`
+		CallData     []byte         `json:"callData"`
+	})
+	if f.maxCalls > 0 && len(calls) > f.maxCalls {
+		return nil, errors.New("out of gas")
+	}
+	f.batches = append(f.batches, len(calls))
+
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	results := make([]result, len(calls))
+	for i, call := range calls {
+		assert.True(f.t, call.AllowFailure)
+		owner := common.BytesToAddress(call.CallData[4:36])
+
+		var balance *big.Int
+		switch {
+		case call.Target == evm.Multicall3Address && bytes.Equal(call.CallData[:4], selector("getEthBalance(address)")):
+			balance = f.native[owner]
+		case bytes.Equal(call.CallData[:4], selector("balanceOf(address)")):
+			if holders, ok := f.tokens[call.Target]; ok {
+				balance = holders[owner]
+			}
+		}
+		if balance != nil {
+			results[i] = result{Success: true, ReturnData: common.LeftPadBytes(balance.Bytes(), 32)}
+		}
+	}
+
+	output, err := method.Outputs.Pack(results)
+	assert.NoError(f.t, err)
+	return output, nil
+}
+
+// selector returns the selector of a method signature.
+func selector(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
+
+// TestManager_GetBalances tests reading native and ERC20 balances of several addresses in batches.
+// go test -v -cover ./pkg/evm -run TestManager_GetBalances
+func TestManager_GetBalances(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl)
+	evm.WithMulticallConfig(evm.MulticallConfig{BatchSize: 4})(manager)
+
+	addresses := []utils.Address{generateRandomAddress(), generateRandomAddress(), generateRandomAddress()}
+	usdc, usdcAddress := newERC20Token()
+	broken, _ := newERC20Token() // Not a contract, so every read fails
+	tokens := []utils.Token{{Type: utils.Native}, usdc, broken}
+
+	fake := &fakeMulticall{
+		t:      t,
+		native: map[common.Address]*big.Int{},
+		tokens: map[common.Address]map[common.Address]*big.Int{usdcAddress: {}},
+	}
+	for i, address := range addresses {
+		fake.native[common.HexToAddress(address.String())] = big.NewInt(int64(1000 + i))
+		fake.tokens[usdcAddress][common.HexToAddress(address.String())] = big.NewInt(int64(i))
+	}
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).DoAndReturn(fake.call).Times(3)
+
+	// Act
+	balances, err := manager.GetBalances(context.Background(), addresses, tokens)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []int{4, 4, 1}, fake.batches)
+	assert.Len(t, balances, 3)
+	for i := range addresses {
+		assert.Len(t, balances[i], 3)
+		assert.Equal(t, big.NewInt(int64(1000+i)), balances[i][0])
+		assert.Equal(t, 0, balances[i][1].Cmp(big.NewInt(int64(i))))
+		assert.Nil(t, balances[i][2])
+	}
+}
+
+// TestManager_GetBalances_SplitsLargeBatches tests that batches rejected for their size are split.
+// go test -v -cover ./pkg/evm -run TestManager_GetBalances_SplitsLargeBatches
+func TestManager_GetBalances_SplitsLargeBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl)
+
+	addresses := make([]utils.Address, 10)
+	fake := &fakeMulticall{t: t, native: map[common.Address]*big.Int{}, maxCalls: 3}
+	for i := range addresses {
+		addresses[i] = generateRandomAddress()
+		fake.native[common.HexToAddress(addresses[i].String())] = big.NewInt(int64(i + 1))
+	}
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).DoAndReturn(fake.call).AnyTimes()
+
+	// Act
+	balances, err := manager.GetBalances(context.Background(), addresses, []utils.Token{{Type: utils.Native}})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 2, 3}, fake.batches) // 10 -> 5 + 5 -> (2 + 3) + (2 + 3)
+	for i := range addresses {
+		assert.Equal(t, big.NewInt(int64(i+1)), balances[i][0])
+	}
+}
+
+// TestManager_GetBalances_Errors tests that node failures and invalid input are reported.
+// go test -v -cover ./pkg/evm -run TestManager_GetBalances_Errors
+func TestManager_GetBalances_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl)
+	addresses := []utils.Address{generateRandomAddress(), generateRandomAddress()}
+
+	// Failures unrelated to the batch size are not retried
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(nil, errors.New("connection refused"))
+
+	_, err := manager.GetBalances(context.Background(), addresses, []utils.Token{{Type: utils.Native}})
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToAggregateCalls)
+	assert.ErrorContains(t, err, "connection refused")
+
+	// Size errors are split whatever their code, while rate limits are not
+	fake := &fakeMulticall{t: t, native: map[common.Address]*big.Int{}}
+	for i, address := range addresses {
+		fake.native[common.HexToAddress(address.String())] = big.NewInt(int64(i + 1))
+	}
+	gomock.InOrder(
+		mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+			Return(nil, testRPCCodeError{code: -32005, msg: "response size exceeded"}),
+		mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).DoAndReturn(fake.call).Times(2),
+	)
+	balances, err := manager.GetBalances(context.Background(), addresses, []utils.Token{{Type: utils.Native}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 1}, fake.batches)
+	assert.Equal(t, big.NewInt(2), balances[1][0])
+
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		Return(nil, testRPCCodeError{code: -32005, msg: "daily request count exceeded, request rate limited"})
+	_, err = manager.GetBalances(context.Background(), addresses, []utils.Token{{Type: utils.Native}})
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToAggregateCalls)
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(nil, errors.New("limit exceeded"))
+	_, err = manager.GetBalances(context.Background(), addresses, []utils.Token{{Type: utils.Native}})
+	assert.ErrorContains(t, err, "limit exceeded")
+
+	// Unsupported tokens
+	_, err = manager.GetBalances(context.Background(), addresses, []utils.Token{{Type: utils.SPLToken}})
+	assert.ErrorContains(t, err, utils.ErrUnsupportedTokenType)
+
+	// ERC1155 tokens need an ID
+	erc1155 := newERC1155Tokens(1)[0]
+	erc1155.ID = nil
+	_, err = manager.GetBalances(context.Background(), addresses, []utils.Token{erc1155})
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidToken)
+}
@@ -0,0 +1,185 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// NonceSource fetches the pending nonce of an account from the chain.
+type NonceSource interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+// NonceManager hands out transaction nonces per address from a local counter, so that
+// concurrent senders sharing a hot wallet never reuse a nonce. It is safe for concurrent
+// use and can be shared by several Manager instances on the same chain.
+type NonceManager struct {
+	mu       sync.Mutex
+	accounts map[common.Address]*accountNonces
+}
+
+// accountNonces holds the nonce state of a single address.
+type accountNonces struct {
+	mu       sync.Mutex
+	synced   bool
+	next     uint64
+	released []uint64 // Released nonces below next, sorted ascending
+}
+
+// NewNonceManager creates a new NonceManager instance.
+func NewNonceManager() *NonceManager {
+	return &NonceManager{
+		accounts: make(map[common.Address]*accountNonces),
+	}
+}
+
+// Next reserves a nonce for the account. Released nonces are handed out first so that
+// gaps are filled. The account is synced with the chain the first time it is used.
+func (nm *NonceManager) Next(ctx context.Context, source NonceSource, account common.Address) (uint64, error) {
+	state := nm.account(account)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if !state.synced {
+		pending, err := source.PendingNonceAt(ctx, account)
+		if err != nil {
+			return 0, err
+		}
+		state.sync(pending)
+	}
+
+	if len(state.released) > 0 {
+		nonce := state.released[0]
+		state.released = state.released[1:]
+		return nonce, nil
+	}
+
+	nonce := state.next
+	state.next++
+	return nonce, nil
+}
+
+// Release returns a reserved nonce that was never broadcast, so it can be handed out again.
+// Nonces that were not reserved by the manager are ignored.
+func (nm *NonceManager) Release(account common.Address, nonce uint64) {
+	state := nm.account(account)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if !state.synced || nonce >= state.next {
+		return
+	}
+
+	i := sort.Search(len(state.released), func(i int) bool { return state.released[i] >= nonce })
+	if i < len(state.released) && state.released[i] == nonce {
+		return
+	}
+	state.released = append(state.released, 0)
+	copy(state.released[i+1:], state.released[i:])
+	state.released[i] = nonce
+
+	// Shrink the counter instead of keeping released nonces at the top of the range
+	for len(state.released) > 0 && state.released[len(state.released)-1] == state.next-1 {
+		state.released = state.released[:len(state.released)-1]
+		state.next--
+	}
+}
+
+// Sync reconciles the account with the chain's pending nonce. The local counter only
+// moves forward, so nonces reserved by in-flight sends are never handed out twice.
+func (nm *NonceManager) Sync(ctx context.Context, source NonceSource, account common.Address) error {
+	state := nm.account(account)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	pending, err := source.PendingNonceAt(ctx, account)
+	if err != nil {
+		return err
+	}
+	state.sync(pending)
+	return nil
+}
+
+// Reset forgets the local state of the account, so the next reservation resyncs with the chain.
+func (nm *NonceManager) Reset(account common.Address) {
+	state := nm.account(account)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.synced = false
+	state.next = 0
+	state.released = nil
+}
+
+// account returns the nonce state of the account, creating it if needed.
+func (nm *NonceManager) account(account common.Address) *accountNonces {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+
+	state, ok := nm.accounts[account]
+	if !ok {
+		state = &accountNonces{}
+		nm.accounts[account] = state
+	}
+	return state
+}
+
+// sync moves the counter up to the pending nonce and drops released nonces the chain has used.
+func (s *accountNonces) sync(pending uint64) {
+	if !s.synced || pending > s.next {
+		s.next = pending
+	}
+	s.synced = true
+
+	i := sort.Search(len(s.released), func(i int) bool { return s.released[i] >= pending })
+	s.released = s.released[i:]
+}
+
+// nextNonce returns the nonce for the next transaction of the account.
+func (m *Manager) nextNonce(ctx context.Context, account common.Address) (uint64, error) {
+	if m.nonceManager != nil {
+		return m.nonceManager.Next(ctx, m.client, account)
+	}
+	return m.client.PendingNonceAt(ctx, account)
+}
+
+// releaseNonce returns the nonce of a transaction that was never broadcast to the nonce manager.
+func (m *Manager) releaseNonce(tx utils.Transaction) {
+	nonce, ok := tx.Payload()["nonce"].(uint64)
+	if !ok || tx.From() == nil {
+		return
+	}
+	m.nonceManager.Release(common.HexToAddress(tx.From().String()), nonce)
+}
+
+// recoverNonce updates the nonce manager after a failed send. A nonce that is too low
+// means the local counter fell behind the chain, so it is synced again. Otherwise the
+// reserved nonce is released, unless the transaction may have reached the node.
+func (m *Manager) recoverNonce(ctx context.Context, tx utils.Transaction, sendErr error, reserved bool) {
+	if m.nonceManager == nil || tx.From() == nil {
+		return
+	}
+
+	switch {
+	case utils.IsError(sendErr, utils.ErrEVMNonceTooLow):
+		// A failed sync leaves the counter as is; the next nonce too low error retries it
+		_ = m.nonceManager.Sync(ctx, m.client, common.HexToAddress(tx.From().String()))
+	case reserved && !isBroadcastUncertain(sendErr):
+		m.releaseNonce(tx)
+	}
+}
+
+// isBroadcastUncertain reports whether a send error leaves it unknown if the node received the transaction.
+func isBroadcastUncertain(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
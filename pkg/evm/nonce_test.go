@@ -0,0 +1,250 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNonceSource returns a fixed pending nonce and counts how often it is queried.
+type fakeNonceSource struct {
+	pending uint64
+	calls   atomic.Int32
+}
+
+func (s *fakeNonceSource) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	s.calls.Add(1)
+	return s.pending, nil
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestNonceManager_Next_Concurrent
+func TestNonceManager_Next_Concurrent(t *testing.T) {
+	nonceManager := evm.NewNonceManager()
+	source := &fakeNonceSource{pending: 5}
+	account := common.HexToAddress(generateRandomAddress().String())
+
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := nonceManager.Next(context.Background(), source, account)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.False(t, seen[nonce], "nonce %d handed out twice", nonce)
+			seen[nonce] = true
+		}()
+	}
+	wg.Wait()
+
+	// Every nonce from the pending nonce onwards is used exactly once, after a single sync
+	assert.Len(t, seen, 100)
+	for nonce := uint64(5); nonce < 105; nonce++ {
+		assert.True(t, seen[nonce])
+	}
+	assert.Equal(t, int32(1), source.calls.Load())
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestNonceManager_Release
+func TestNonceManager_Release(t *testing.T) {
+	nonceManager := evm.NewNonceManager()
+	source := &fakeNonceSource{pending: 0}
+	account := common.HexToAddress(generateRandomAddress().String())
+
+	for i := uint64(0); i < 3; i++ {
+		nonce, err := nonceManager.Next(context.Background(), source, account)
+		assert.NoError(t, err)
+		assert.Equal(t, i, nonce)
+	}
+
+	// A released nonce in the middle of the range is handed out again first
+	nonceManager.Release(account, 1)
+	nonceManager.Release(account, 1)
+	nonce, err := nonceManager.Next(context.Background(), source, account)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), nonce)
+
+	// Releasing the highest nonce shrinks the counter
+	nonceManager.Release(account, 2)
+	nonce, err = nonceManager.Next(context.Background(), source, account)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), nonce)
+
+	// Nonces that were never reserved are ignored
+	nonceManager.Release(account, 10)
+	nonce, err = nonceManager.Next(context.Background(), source, account)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), nonce)
+}
+
+// To run this specific test from the root directory with coverage and verbosity:
+// go test -v -cover ./pkg/evm -run TestNonceManager_Sync
+func TestNonceManager_Sync(t *testing.T) {
+	nonceManager := evm.NewNonceManager()
+	source := &fakeNonceSource{pending: 3}
+	account := common.HexToAddress(generateRandomAddress().String())
+
+	for i := 0; i < 4; i++ {
+		_, err := nonceManager.Next(context.Background(), source, account)
+		assert.NoError(t, err)
+	}
+
+	// The chain lagging behind in-flight reservations does not move the counter back
+	assert.NoError(t, nonceManager.Sync(context.Background(), source, account))
+	nonce, err := nonceManager.Next(context.Background(), source, account)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), nonce)
+
+	// The chain running ahead moves the counter forward
+	source.pending = 20
+	assert.NoError(t, nonceManager.Sync(context.Background(), source, account))
+	nonce, err = nonceManager.Next(context.Background(), source, account)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), nonce)
+
+	// Resetting resyncs from the chain on the next reservation
+	source.pending = 4
+	nonceManager.Reset(account)
+	nonce, err = nonceManager.Next(context.Background(), source, account)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), nonce)
+}
+
+// TestManager_SendTransaction_NonceManager tests that managers sharing a nonce manager release and resync nonces.
+// go test -v -cover ./pkg/evm -run TestManager_SendTransaction_NonceManager
+func TestManager_SendTransaction_NonceManager(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	signer, err := evm.NewPrivateKeySigner(fmt.Sprintf("%x", crypto.FromECDSA(privateKey)))
+	assert.NoError(t, err)
+	from, err := evm.NewAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	// Two managers share the same nonce manager
+	nonceManager := evm.NewNonceManager()
+	newManager := func() (*mock_evm.MockClientInterface, func(utils.Transaction) (string, error)) {
+		mockClient := mock_evm.NewMockClientInterface(ctrl)
+		mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+		mockClientFactory.EXPECT().DialContext(gomock.Any(), gomock.Any()).Return(mockClient, nil)
+
+		manager := evm.NewManager("http://localhost:8545", signer, mockClientFactory, utils.Ethereum,
+			evm.WithAutoPrepare(), evm.WithNonceManager(nonceManager))
+		manager.Start(context.Background())
+		return mockClient, func(tx utils.Transaction) (string, error) {
+			return manager.SendTransaction(context.Background(), tx)
+		}
+	}
+	clientA, sendA := newManager()
+	clientB, sendB := newManager()
+
+	newTransfer := func() utils.Transaction {
+		txType := utils.Transfer
+		return evm.NewTransaction(nil, from, generateRandomAddress(), big.NewInt(1), &txType, nil, nil, nil,
+			21000, big.NewInt(1), big.NewInt(1), 0, nil)
+	}
+	var sentNonces []uint64
+	recordNonce := func(_ context.Context, tx *types.Transaction) error {
+		sentNonces = append(sentNonces, tx.Nonce())
+		return nil
+	}
+
+	// The first reservation syncs with the chain
+	clientA.EXPECT().PendingNonceAt(gomock.Any(), gomock.Any()).Return(uint64(7), nil)
+	clientA.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).DoAndReturn(recordNonce)
+	_, err = sendA(newTransfer())
+	assert.NoError(t, err)
+
+	// A send rejected before broadcast releases its nonce for the next send
+	clientB.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).Return(errors.New("insufficient funds for gas * price + value"))
+	_, err = sendB(newTransfer())
+	assert.ErrorContains(t, err, utils.ErrEVMInsufficientFunds)
+
+	clientB.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).DoAndReturn(recordNonce)
+	_, err = sendB(newTransfer())
+	assert.NoError(t, err)
+
+	// A nonce too low error resyncs the shared counter with the chain
+	clientA.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).Return(errors.New("nonce too low"))
+	clientA.EXPECT().PendingNonceAt(gomock.Any(), gomock.Any()).Return(uint64(15), nil)
+	_, err = sendA(newTransfer())
+	assert.ErrorContains(t, err, utils.ErrEVMNonceTooLow)
+
+	clientB.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).DoAndReturn(recordNonce)
+	_, err = sendB(newTransfer())
+	assert.NoError(t, err)
+
+	assert.Equal(t, []uint64{7, 8, 15}, sentNonces)
+}
+
+// TestManager_SendTransaction_NonceManager_PrepareError tests that accounts are synced on start and failed preparations keep their nonce.
+// go test -v -cover ./pkg/evm -run TestManager_SendTransaction_NonceManager_PrepareError
+func TestManager_SendTransaction_NonceManager_PrepareError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	signer, err := evm.NewPrivateKeySigner(fmt.Sprintf("%x", crypto.FromECDSA(privateKey)))
+	assert.NoError(t, err)
+	from, err := evm.NewAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), gomock.Any()).Return(mockClient, nil).Times(2)
+
+	manager := evm.NewManager("http://localhost:8545", signer, mockClientFactory, utils.Ethereum,
+		evm.WithAutoPrepare(), evm.WithNonceManager(evm.NewNonceManager(), from))
+
+	// A failed sync fails the start
+	mockClient.EXPECT().PendingNonceAt(gomock.Any(), common.HexToAddress(from.String())).Return(uint64(0), errors.New("connection refused"))
+	mockClient.EXPECT().Close()
+	assert.ErrorContains(t, manager.Start(context.Background()), "connection refused")
+
+	// The account is synced on start
+	mockClient.EXPECT().PendingNonceAt(gomock.Any(), common.HexToAddress(from.String())).Return(uint64(4), nil)
+	assert.NoError(t, manager.Start(context.Background()))
+
+	newTransfer := func() utils.Transaction {
+		txType := utils.Transfer
+		return evm.NewTransaction(nil, from, generateRandomAddress(), big.NewInt(1), &txType, nil, nil, nil,
+			0, big.NewInt(1), big.NewInt(1), 0, nil)
+	}
+
+	// A reverting gas estimate does not use up a nonce
+	mockClient.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(0), errors.New("execution reverted"))
+	_, err = manager.SendTransaction(context.Background(), newTransfer())
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToPrepareTransaction)
+
+	var sentNonce uint64
+	mockClient.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(21000), nil)
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+		sentNonce = tx.Nonce()
+		return nil
+	})
+	_, err = manager.SendTransaction(context.Background(), newTransfer())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), sentNonce)
+}
@@ -0,0 +1,112 @@
+package evm
+
+import (
+	"time"
+
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// ManagerOption configures optional behaviour of a Manager.
+type ManagerOption func(*Manager)
+
+// WithGasMargin sets the safety margin, in percent, added on top of the node's gas estimate.
+func WithGasMargin(percent uint64) ManagerOption {
+	return func(m *Manager) {
+		m.gasMarginPercent = percent
+	}
+}
+
+// WithAutoPrepare makes SendTransaction run PrepareTransaction before signing, so callers
+// only need to provide the sender, recipient and amount.
+func WithAutoPrepare() ManagerOption {
+	return func(m *Manager) {
+		m.autoPrepare = true
+	}
+}
+
+// WithNonceManager makes the Manager reserve nonces from a shared NonceManager instead of
+// querying the pending nonce for every transaction. The given accounts are synced with
+// the chain when the Manager starts; other accounts are synced on first use.
+func WithNonceManager(nonceManager *NonceManager, accounts ...utils.Address) ManagerOption {
+	return func(m *Manager) {
+		m.nonceManager = nonceManager
+		m.nonceAccounts = accounts
+	}
+}
+
+// WithFeeOracleConfig overrides the network's default fee oracle configuration. Fields left
+// unset keep their network default, such as Polygon's minimum tip.
+func WithFeeOracleConfig(config FeeOracleConfig) ManagerOption {
+	return func(m *Manager) {
+		m.feeOracleConfig = &config
+	}
+}
+
+// WithFeeSpeed sets the speed tier PrepareTransaction prices transactions at, standard by default.
+func WithFeeSpeed(speed FeeSpeed) ManagerOption {
+	return func(m *Manager) {
+		m.feeSpeed = speed
+	}
+}
+
+// WithReplacementBump sets the minimum fee increase, in percent, of the transactions sent by
+// SpeedUpTransaction and CancelTransaction. It defaults to 10, the threshold of geth's pool.
+func WithReplacementBump(percent uint64) ManagerOption {
+	return func(m *Manager) {
+		m.replacementBumpPercent = percent
+	}
+}
+
+// WithPollInterval sets how often the Manager polls the node when new heads cannot be
+// subscribed to. It defaults to 2 seconds.
+func WithPollInterval(interval time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.pollEvery = interval
+	}
+}
+
+// WithDroppedTimeout sets how long WaitForConfirmation waits for a transaction the node does
+// not know before reporting it dropped, while its nonce is still unused. It defaults to 1 minute.
+func WithDroppedTimeout(timeout time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.droppedAfter = timeout
+	}
+}
+
+// WithTracker makes the Manager follow every transaction it sends until it is final, with a
+// background goroutine started and stopped along with the Manager.
+func WithTracker(config TrackerConfig) ManagerOption {
+	return func(m *Manager) {
+		m.tracker = newTracker(config)
+	}
+}
+
+// WithLogQueryConfig sets how GetLogs splits large block ranges.
+func WithLogQueryConfig(config LogQueryConfig) ManagerOption {
+	return func(m *Manager) {
+		m.logQueryConfig = config
+	}
+}
+
+// WithMulticallConfig sets the Multicall3 contract and batch size GetBalances uses.
+func WithMulticallConfig(config MulticallConfig) ManagerOption {
+	return func(m *Manager) {
+		m.multicallConfig = config
+	}
+}
+
+// WithEventRegistry sets the registry transaction events are decoded with, instead of a
+// registry with the built-in token events only.
+func WithEventRegistry(registry *EventRegistry) ManagerOption {
+	return func(m *Manager) {
+		m.eventRegistry = registry
+	}
+}
+
+// WithTokenResolver sets the resolver token metadata is resolved with, so that it can be
+// shared by the Managers of several networks or backed by a TokenStore.
+func WithTokenResolver(resolver *TokenResolver) ManagerOption {
+	return func(m *Manager) {
+		m.tokenResolver = resolver
+	}
+}
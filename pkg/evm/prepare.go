@@ -0,0 +1,97 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// PrepareTransaction fills in the payload fields a transaction needs before it can be
+// signed, leaving any field the caller already set untouched:
+//   - "chainId" from the node's chain ID
+//   - "maxFeePerGas"/"maxPriorityFeePerGas", or "gasPrice" on chains without a base fee,
+//     from the fee oracle at the configured fee speed
+//   - "gasLimit" from EstimateGas, including the configured gas margin
+//   - "nonce" from the nonce manager, or the sender's pending nonce without one
+func (m *Manager) PrepareTransaction(ctx context.Context, tx utils.Transaction) error {
+	if m.client == nil {
+		return utils.WrapError(utils.ErrClientNotStarted)
+	}
+	if tx == nil {
+		return utils.WrapError(utils.ErrEVMInvalidTransaction)
+	}
+	if tx.From() == nil || tx.From().String() == "" {
+		return utils.WrapError(utils.ErrEVMInvalidTransaction, errors.New("missing sender address"))
+	}
+
+	payload := tx.Payload()
+
+	if _, ok := payload["chainId"]; !ok {
+		chainID, err := m.client.ChainID(ctx)
+		if err != nil {
+			return utils.WrapError(utils.ErrEVMFailedToPrepareTransaction, fmt.Errorf("failed to fetch chain ID: %w", err))
+		}
+		tx.SetPayload("chainId", chainID)
+	}
+
+	if !hasFeeFields(payload) {
+		if err := m.suggestFees(ctx, tx); err != nil {
+			return utils.WrapError(utils.ErrEVMFailedToPrepareTransaction, err)
+		}
+	}
+
+	if _, ok := payload["gasLimit"]; !ok {
+		gasLimit, err := m.EstimateGas(ctx, tx)
+		if err != nil {
+			return utils.WrapError(utils.ErrEVMFailedToPrepareTransaction, err)
+		}
+		if !gasLimit.IsUint64() {
+			return utils.WrapError(utils.ErrEVMFailedToPrepareTransaction, errors.New("gas estimate overflows uint64"))
+		}
+		tx.SetPayload("gasLimit", gasLimit.Uint64())
+	}
+
+	// The nonce is reserved last, so a failing fee or gas lookup never leaks one
+	if _, ok := payload["nonce"]; !ok {
+		nonce, err := m.nextNonce(ctx, common.HexToAddress(tx.From().String()))
+		if err != nil {
+			return utils.WrapError(utils.ErrEVMFailedToPrepareTransaction, fmt.Errorf("failed to fetch nonce: %w", err))
+		}
+		tx.SetPayload("nonce", nonce)
+	}
+
+	return nil
+}
+
+// SuggestFees returns slow, standard and fast fee suggestions from the Manager's fee oracle.
+// Callers building transactions can apply a tier to their payload with FeeSuggestion.Apply.
+func (m *Manager) SuggestFees(ctx context.Context) (*FeeSuggestions, error) {
+	if m.client == nil {
+		return nil, utils.WrapError(utils.ErrClientNotStarted)
+	}
+	return m.feeOracle.SuggestFees(ctx)
+}
+
+// suggestFees prices the transaction at the Manager's fee speed, using dynamic fees when the
+// chain has a base fee and a legacy gas price otherwise.
+func (m *Manager) suggestFees(ctx context.Context, tx utils.Transaction) error {
+	suggestions, err := m.feeOracle.SuggestFees(ctx)
+	if err != nil {
+		return err
+	}
+	suggestions.Speed(m.feeSpeed).Apply(tx)
+	return nil
+}
+
+// hasFeeFields reports whether the payload already prices the transaction.
+func hasFeeFields(payload map[string]interface{}) bool {
+	for _, key := range []string{"gasPrice", "maxFeePerGas", "maxPriorityFeePerGas"} {
+		if _, ok := payload[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	mock_signer "github.com/mselser95/blockchain/internal/mock/signer"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestManager_PrepareTransaction_DynamicFees tests that PrepareTransaction fills every missing field on a London chain.
+// go test -v -cover ./pkg/evm -run TestManager_PrepareTransaction_DynamicFees
+func TestManager_PrepareTransaction_DynamicFees(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum,
+		evm.WithGasMargin(10)).(*evm.Manager)
+	manager.Start(context.Background())
+
+	from := generateRandomAddress()
+	txType := utils.Transfer
+	tx := evm.NewTransaction(nil, from, generateRandomAddress(), big.NewInt(1000), &txType, nil, nil, nil,
+		0, nil, nil, 0, nil)
+
+	mockClient.EXPECT().ChainID(gomock.Any()).Return(big.NewInt(10), nil)
+	mockClient.EXPECT().PendingNonceAt(gomock.Any(), common.HexToAddress(from.String())).Return(uint64(5), nil)
+	mockClient.EXPECT().FeeHistory(gomock.Any(), uint64(20), nil, []float64{10, 50, 90}).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(90), big.NewInt(100)},
+		Reward:       [][]*big.Int{{big.NewInt(1), big.NewInt(2), big.NewInt(3)}},
+		GasUsedRatio: []float64{0.5},
+	}, nil)
+	mockClient.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(21000), nil)
+
+	// Act
+	err := manager.PrepareTransaction(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Validate())
+	assert.Equal(t, big.NewInt(10), tx.Payload()["chainId"])
+	assert.Equal(t, uint64(5), tx.Payload()["nonce"])
+	assert.Equal(t, big.NewInt(2), tx.Payload()["maxPriorityFeePerGas"])
+	assert.Equal(t, big.NewInt(202), tx.Payload()["maxFeePerGas"]) // 2 * next BaseFee (100) + median tip (2)
+	assert.Equal(t, uint64(23100), tx.Payload()["gasLimit"])       // 21000 + 10%
+}
+
+// TestManager_PrepareTransaction_LegacyFees tests that PrepareTransaction uses a gas price on chains without a base fee.
+// go test -v -cover ./pkg/evm -run TestManager_PrepareTransaction_LegacyFees
+func TestManager_PrepareTransaction_LegacyFees(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Bsc).(*evm.Manager)
+	manager.Start(context.Background())
+
+	txType := utils.Transfer
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(1000), &txType,
+		nil, nil, nil, 21000, nil, big.NewInt(56), 3, nil)
+
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(nil, errors.New("method not found"))
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), nil).Return(&types.Header{}, nil)
+	mockClient.EXPECT().SuggestGasPrice(gomock.Any()).Return(big.NewInt(3000000000), nil)
+
+	// Act
+	err := manager.PrepareTransaction(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Validate())
+	assert.Equal(t, big.NewInt(3000000000), tx.Payload()["gasPrice"])
+	assert.Equal(t, uint64(21000), tx.Payload()["gasLimit"])
+	assert.Equal(t, uint64(3), tx.Payload()["nonce"])
+}
+
+// TestManager_PrepareTransaction_NonceError tests that PrepareTransaction reports node failures.
+// go test -v -cover ./pkg/evm -run TestManager_PrepareTransaction_NonceError
+func TestManager_PrepareTransaction_NonceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockSigner := mock_signer.NewMockTransactionSigner(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	manager := evm.NewManager("http://localhost:8545", mockSigner, mockClientFactory, utils.Ethereum).(*evm.Manager)
+	manager.Start(context.Background())
+
+	txType := utils.Transfer
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(1000), &txType,
+		nil, nil, nil, 21000, big.NewInt(1), big.NewInt(1), 0, nil)
+
+	mockClient.EXPECT().PendingNonceAt(gomock.Any(), gomock.Any()).Return(uint64(0), errors.New("connection refused"))
+
+	// Act
+	err := manager.PrepareTransaction(context.Background(), tx)
+
+	// Assert
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToPrepareTransaction)
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+// TestManager_SendTransaction_AutoPrepare tests that a transfer with only sender, recipient and amount can be sent.
+// go test -v -cover ./pkg/evm -run TestManager_SendTransaction_AutoPrepare
+func TestManager_SendTransaction_AutoPrepare(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	signer, err := evm.NewPrivateKeySigner(fmt.Sprintf("%x", crypto.FromECDSA(privateKey)))
+	assert.NoError(t, err)
+	from, err := evm.NewAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+
+	manager := evm.NewManager("http://localhost:8545", signer, mockClientFactory, utils.Ethereum, evm.WithAutoPrepare())
+	manager.Start(context.Background())
+
+	mockClient.EXPECT().ChainID(gomock.Any()).Return(big.NewInt(1), nil)
+	mockClient.EXPECT().PendingNonceAt(gomock.Any(), gomock.Any()).Return(uint64(9), nil)
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(10), big.NewInt(10)},
+		Reward:       [][]*big.Int{{big.NewInt(1), big.NewInt(1), big.NewInt(1)}},
+		GasUsedRatio: []float64{0.5},
+	}, nil)
+	mockClient.EXPECT().EstimateGas(gomock.Any(), gomock.Any()).Return(uint64(21000), nil)
+
+	var sent *types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = tx
+			return nil
+		})
+
+	txType := utils.Transfer
+	tx := &evm.BaseTransaction{
+		FromAddress: from,
+		ToAddress:   generateRandomAddress(),
+		TxAmount:    big.NewInt(1000),
+		TxType:      &txType,
+		TxPayload:   map[string]interface{}{},
+	}
+
+	// Act
+	hash, err := manager.SendTransaction(context.Background(), tx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sent.Hash().Hex(), hash)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), sent.Type())
+	assert.Equal(t, uint64(9), sent.Nonce())
+	assert.Equal(t, uint64(21000), sent.Gas())
+}
@@ -0,0 +1,539 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// RateLimitConfig configures the request budget of an endpoint.
+type RateLimitConfig struct {
+	// Rate is the budget refilled every second, in requests or, with Weights, in compute
+	// units. Zero disables the limit.
+	Rate float64
+	// Burst is the most budget that builds up while the endpoint is idle, Rate by default.
+	Burst float64
+	// Weights is the cost of calls by ClientInterface method name, such as "FilterLogs".
+	// Calls to methods not listed cost 1.
+	Weights map[string]float64
+}
+
+// RateLimitUsage is a snapshot of the budget usage of a RateLimiter.
+type RateLimitUsage struct {
+	// Requests is the number of calls made.
+	Requests uint64
+	// Units is the budget spent by the calls made.
+	Units float64
+	// Throttled is the number of calls that had to wait for budget.
+	Throttled uint64
+	// WaitTime is the total time calls waited for budget.
+	WaitTime time.Duration
+	// Waiting is the number of calls waiting for budget right now.
+	Waiting int
+	// Available is the budget left right now, negative while calls are waiting.
+	Available float64
+	// Methods is the number of calls made by method name.
+	Methods map[string]uint64
+}
+
+// RateLimiter is a token bucket holding the request budget of an endpoint. Calls wait for
+// their budget in the order they arrive, so a burst of cheap calls cannot starve an
+// expensive one queued before them.
+type RateLimiter struct {
+	config RateLimitConfig
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	usage  RateLimitUsage
+}
+
+// NewRateLimiter creates a new RateLimiter instance starting with a full budget.
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.Burst <= 0 {
+		config.Burst = max(config.Rate, 1)
+	}
+
+	return &RateLimiter{
+		config: config,
+		tokens: config.Burst,
+		last:   time.Now(),
+		usage:  RateLimitUsage{Methods: map[string]uint64{}},
+	}
+}
+
+// Wait blocks until the budget of a call to method is available and spends it. Calls more
+// expensive than the burst are let through once the budget is full, leaving it in debt. An
+// error is returned, and the budget given back, when the context is done first.
+func (l *RateLimiter) Wait(ctx context.Context, method string) error {
+	weight := 1.0
+	if w, ok := l.config.Weights[method]; ok {
+		weight = w
+	}
+
+	l.mu.Lock()
+	l.usage.Requests++
+	l.usage.Units += weight
+	l.usage.Methods[method]++
+	if l.config.Rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	// Every call takes its budget at once, going into debt when there is not enough. The
+	// debt delays the calls behind it, which keeps them in arrival order.
+	l.refill(time.Now())
+	debt := min(weight, l.config.Burst) - l.tokens
+	l.tokens -= weight
+	if debt <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(debt / l.config.Rate * float64(time.Second))
+	l.usage.Throttled++
+	l.usage.Waiting++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	start := time.Now()
+	var err error
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = utils.WrapError(utils.ErrEVMRateLimited, ctx.Err())
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.usage.Waiting--
+	l.usage.WaitTime += time.Since(start)
+	if err != nil {
+		l.refill(time.Now())
+		l.tokens = min(l.tokens+weight, l.config.Burst)
+		l.usage.Requests--
+		l.usage.Units -= weight
+		l.usage.Methods[method]--
+	}
+	return err
+}
+
+// Usage returns a snapshot of the budget usage.
+func (l *RateLimiter) Usage() RateLimitUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.Rate > 0 {
+		l.refill(time.Now())
+	}
+	usage := l.usage
+	usage.Available = l.tokens
+	usage.Methods = make(map[string]uint64, len(l.usage.Methods))
+	for method, count := range l.usage.Methods {
+		if count > 0 {
+			usage.Methods[method] = count
+		}
+	}
+	return usage
+}
+
+// refill adds the budget accrued since the last refill. It must be called with the lock held.
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	l.tokens = min(l.tokens+elapsed*l.config.Rate, l.config.Burst)
+}
+
+// RateLimitedClient is a ClientInterface that spends the budget of a RateLimiter on every
+// call, waiting in line while the budget is exhausted instead of failing. Raw requests sent
+// through CallContext and BatchCallContext spend the budget of their JSON-RPC method, once
+// per request of a batch.
+type RateLimitedClient struct {
+	ClientInterface
+	limiter *RateLimiter
+}
+
+// NewRateLimitedClient creates a new RateLimitedClient instance spending the budget of the
+// limiter, which may be shared with other clients of the same endpoint.
+func NewRateLimitedClient(client ClientInterface, limiter *RateLimiter) *RateLimitedClient {
+	return &RateLimitedClient{ClientInterface: client, limiter: limiter}
+}
+
+// Usage returns a snapshot of the budget usage of the client's endpoint.
+func (c *RateLimitedClient) Usage() RateLimitUsage {
+	return c.limiter.Usage()
+}
+
+// ChainID retrieves the current chain ID.
+func (c *RateLimitedClient) ChainID(ctx context.Context) (*big.Int, error) {
+	if err := c.limiter.Wait(ctx, "ChainID"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.ChainID(ctx)
+}
+
+// BlockByHash returns the given full block.
+func (c *RateLimitedClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if err := c.limiter.Wait(ctx, "BlockByHash"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.BlockByHash(ctx, hash)
+}
+
+// BlockByNumber returns a block from the current canonical chain. If number is nil, the
+// latest known block is returned.
+func (c *RateLimitedClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if err := c.limiter.Wait(ctx, "BlockByNumber"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.BlockByNumber(ctx, number)
+}
+
+// BlockNumber returns the most recent block number.
+func (c *RateLimitedClient) BlockNumber(ctx context.Context) (uint64, error) {
+	if err := c.limiter.Wait(ctx, "BlockNumber"); err != nil {
+		return 0, err
+	}
+	return c.ClientInterface.BlockNumber(ctx)
+}
+
+// PeerCount returns the number of p2p peers as reported by the net_peerCount method.
+func (c *RateLimitedClient) PeerCount(ctx context.Context) (uint64, error) {
+	if err := c.limiter.Wait(ctx, "PeerCount"); err != nil {
+		return 0, err
+	}
+	return c.ClientInterface.PeerCount(ctx)
+}
+
+// BlockReceipts returns the receipts of a given block number or hash.
+func (c *RateLimitedClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	if err := c.limiter.Wait(ctx, "BlockReceipts"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.BlockReceipts(ctx, blockNrOrHash)
+}
+
+// NetworkID returns the network ID for this client.
+func (c *RateLimitedClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	if err := c.limiter.Wait(ctx, "NetworkID"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.NetworkID(ctx)
+}
+
+// BalanceAt returns the wei balance of the given account.
+func (c *RateLimitedClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if err := c.limiter.Wait(ctx, "BalanceAt"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.BalanceAt(ctx, account, blockNumber)
+}
+
+// BalanceAtHash returns the wei balance of the given account at the given block hash.
+func (c *RateLimitedClient) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	if err := c.limiter.Wait(ctx, "BalanceAtHash"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.BalanceAtHash(ctx, account, blockHash)
+}
+
+// StorageAt returns the value of key in the contract storage of the given account.
+func (c *RateLimitedClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "StorageAt"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.StorageAt(ctx, account, key, blockNumber)
+}
+
+// StorageAtHash returns the value of key in the contract storage of the given account at the
+// given block hash.
+func (c *RateLimitedClient) StorageAtHash(ctx context.Context, account common.Address, key common.Hash, blockHash common.Hash) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "StorageAtHash"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.StorageAtHash(ctx, account, key, blockHash)
+}
+
+// CodeAt returns the contract code of the given account.
+func (c *RateLimitedClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "CodeAt"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.CodeAt(ctx, account, blockNumber)
+}
+
+// CodeAtHash returns the contract code of the given account at the given block hash.
+func (c *RateLimitedClient) CodeAtHash(ctx context.Context, account common.Address, blockHash common.Hash) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "CodeAtHash"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.CodeAtHash(ctx, account, blockHash)
+}
+
+// NonceAt returns the account nonce of the given account.
+func (c *RateLimitedClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	if err := c.limiter.Wait(ctx, "NonceAt"); err != nil {
+		return 0, err
+	}
+	return c.ClientInterface.NonceAt(ctx, account, blockNumber)
+}
+
+// NonceAtHash returns the account nonce of the given account at the given block hash.
+func (c *RateLimitedClient) NonceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (uint64, error) {
+	if err := c.limiter.Wait(ctx, "NonceAtHash"); err != nil {
+		return 0, err
+	}
+	return c.ClientInterface.NonceAtHash(ctx, account, blockHash)
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If number is
+// nil, the latest known header is returned.
+func (c *RateLimitedClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if err := c.limiter.Wait(ctx, "HeaderByNumber"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.HeaderByNumber(ctx, number)
+}
+
+// PendingBalanceAt returns the wei balance of the given account in the pending state.
+func (c *RateLimitedClient) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	if err := c.limiter.Wait(ctx, "PendingBalanceAt"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.PendingBalanceAt(ctx, account)
+}
+
+// PendingStorageAt returns the value of key in the contract storage of the given account in
+// the pending state.
+func (c *RateLimitedClient) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "PendingStorageAt"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.PendingStorageAt(ctx, account, key)
+}
+
+// PendingCodeAt returns the contract code of the given account in the pending state.
+func (c *RateLimitedClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "PendingCodeAt"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.PendingCodeAt(ctx, account)
+}
+
+// PendingNonceAt returns the account nonce of the given account in the pending state.
+func (c *RateLimitedClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if err := c.limiter.Wait(ctx, "PendingNonceAt"); err != nil {
+		return 0, err
+	}
+	return c.ClientInterface.PendingNonceAt(ctx, account)
+}
+
+// PendingTransactionCount returns the total number of transactions in the pending state.
+func (c *RateLimitedClient) PendingTransactionCount(ctx context.Context) (uint, error) {
+	if err := c.limiter.Wait(ctx, "PendingTransactionCount"); err != nil {
+		return 0, err
+	}
+	return c.ClientInterface.PendingTransactionCount(ctx)
+}
+
+// CallContract executes a message call transaction without creating a transaction on the chain.
+func (c *RateLimitedClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "CallContract"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.CallContract(ctx, msg, blockNumber)
+}
+
+// CallContractAtHash executes a message call transaction at the given block hash.
+func (c *RateLimitedClient) CallContractAtHash(ctx context.Context, msg ethereum.CallMsg, blockHash common.Hash) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "CallContractAtHash"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.CallContractAtHash(ctx, msg, blockHash)
+}
+
+// PendingCallContract executes a message call transaction against the pending state.
+func (c *RateLimitedClient) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	if err := c.limiter.Wait(ctx, "PendingCallContract"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.PendingCallContract(ctx, msg)
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price.
+func (c *RateLimitedClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if err := c.limiter.Wait(ctx, "SuggestGasPrice"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.SuggestGasPrice(ctx)
+}
+
+// SuggestGasTipCap retrieves the currently suggested gas tip cap.
+func (c *RateLimitedClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	if err := c.limiter.Wait(ctx, "SuggestGasTipCap"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.SuggestGasTipCap(ctx)
+}
+
+// FeeHistory retrieves the fee market history.
+func (c *RateLimitedClient) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	if err := c.limiter.Wait(ctx, "FeeHistory"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+}
+
+// EstimateGas estimates the gas needed to execute a specific transaction.
+func (c *RateLimitedClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	if err := c.limiter.Wait(ctx, "EstimateGas"); err != nil {
+		return 0, err
+	}
+	return c.ClientInterface.EstimateGas(ctx, msg)
+}
+
+// TransactionByHash returns the transaction with the given hash.
+func (c *RateLimitedClient) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	if err := c.limiter.Wait(ctx, "TransactionByHash"); err != nil {
+		return nil, false, err
+	}
+	return c.ClientInterface.TransactionByHash(ctx, txHash)
+}
+
+// TransactionReceipt returns the receipt of a transaction by transaction hash.
+func (c *RateLimitedClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if err := c.limiter.Wait(ctx, "TransactionReceipt"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.TransactionReceipt(ctx, txHash)
+}
+
+// SendTransaction injects a signed transaction into the pending pool for execution.
+func (c *RateLimitedClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := c.limiter.Wait(ctx, "SendTransaction"); err != nil {
+		return err
+	}
+	return c.ClientInterface.SendTransaction(ctx, tx)
+}
+
+// FilterLogs executes a filter query.
+func (c *RateLimitedClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	if err := c.limiter.Wait(ctx, "FilterLogs"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.FilterLogs(ctx, q)
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query. Only starting
+// the subscription spends budget.
+func (c *RateLimitedClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if err := c.limiter.Wait(ctx, "SubscribeFilterLogs"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.SubscribeFilterLogs(ctx, q, ch)
+}
+
+// SubscribeNewHead subscribes to notifications about the current blockchain head. Only
+// starting the subscription spends budget.
+func (c *RateLimitedClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	if err := c.limiter.Wait(ctx, "SubscribeNewHead"); err != nil {
+		return nil, err
+	}
+	return c.ClientInterface.SubscribeNewHead(ctx, ch)
+}
+
+// CallContext sends a raw JSON-RPC request, spending the budget of its method.
+func (c *RateLimitedClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	if err := c.limiter.Wait(ctx, method); err != nil {
+		return err
+	}
+	return caller.CallContext(ctx, result, method, args...)
+}
+
+// BatchCallContext sends raw JSON-RPC requests in one batch, spending the budget of every
+// request in it.
+func (c *RateLimitedClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	for _, elem := range b {
+		if err := c.limiter.Wait(ctx, elem.Method); err != nil {
+			return err
+		}
+	}
+	return caller.BatchCallContext(ctx, b)
+}
+
+// RateLimitedClientFactory is a ClientFactory that wraps the clients of another factory in
+// RateLimitedClients. Clients of the same endpoint share its budget.
+type RateLimitedClientFactory struct {
+	// Factory dials the underlying clients, an EthClientFactory when nil.
+	Factory ClientFactory
+	// Endpoints configures the budget of endpoints by URL.
+	Endpoints map[string]RateLimitConfig
+	// Default configures the budget of endpoints not in Endpoints.
+	Default RateLimitConfig
+
+	mu       sync.Mutex
+	limiters map[string]*RateLimiter
+}
+
+// DialContext dials a new client and wraps it in a RateLimitedClient spending the budget of
+// the endpoint.
+func (f *RateLimitedClientFactory) DialContext(ctx context.Context, url string) (ClientInterface, error) {
+	factory := f.Factory
+	if factory == nil {
+		factory = &EthClientFactory{}
+	}
+
+	client, err := factory.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return NewRateLimitedClient(client, f.limiter(url)), nil
+}
+
+// Usage returns a snapshot of the budget usage of every endpoint dialed, by URL.
+func (f *RateLimitedClientFactory) Usage() map[string]RateLimitUsage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	usage := make(map[string]RateLimitUsage, len(f.limiters))
+	for url, limiter := range f.limiters {
+		usage[url] = limiter.Usage()
+	}
+	return usage
+}
+
+// limiter returns the limiter holding the budget of an endpoint, creating it on first use.
+func (f *RateLimitedClientFactory) limiter(url string) *RateLimiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if limiter, ok := f.limiters[url]; ok {
+		return limiter
+	}
+	config, ok := f.Endpoints[url]
+	if !ok {
+		config = f.Default
+	}
+	if f.limiters == nil {
+		f.limiters = map[string]*RateLimiter{}
+	}
+	f.limiters[url] = NewRateLimiter(config)
+	return f.limiters[url]
+}
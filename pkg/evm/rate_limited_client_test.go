@@ -0,0 +1,203 @@
+package evm_test
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForWaiting waits until the given number of calls are waiting for budget.
+func waitForWaiting(t *testing.T, limiter *evm.RateLimiter, waiting int) {
+	assert.Eventually(t, func() bool {
+		return limiter.Usage().Waiting == waiting
+	}, time.Second, time.Millisecond)
+}
+
+// TestRateLimiter_Wait tests that callers over the budget are queued in arrival order.
+// go test -v -cover ./pkg/evm -run TestRateLimiter_Wait
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := evm.NewRateLimiter(evm.RateLimitConfig{Rate: 50})
+
+	// The burst defaults to one second of budget
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, limiter.Wait(context.Background(), "BlockNumber"))
+	}
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// Callers over the budget wait in line instead of failing
+	done := make(chan int, 4)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			assert.NoError(t, limiter.Wait(context.Background(), "BlockNumber"))
+			done <- i
+		}(i)
+		waitForWaiting(t, limiter, i+1)
+	}
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, i, <-done)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+
+	// Usage
+	usage := limiter.Usage()
+	assert.Equal(t, uint64(54), usage.Requests)
+	assert.Equal(t, float64(54), usage.Units)
+	assert.Equal(t, uint64(4), usage.Throttled)
+	assert.Equal(t, 0, usage.Waiting)
+	assert.Greater(t, usage.WaitTime, time.Duration(0))
+	assert.Equal(t, map[string]uint64{"BlockNumber": 54}, usage.Methods)
+}
+
+// TestRateLimiter_Weights tests that calls spend the budget of their method's weight.
+// go test -v -cover ./pkg/evm -run TestRateLimiter_Weights
+func TestRateLimiter_Weights(t *testing.T) {
+	limiter := evm.NewRateLimiter(evm.RateLimitConfig{
+		Rate:    1000,
+		Burst:   100,
+		Weights: map[string]float64{"FilterLogs": 75, "TraceBlock": 250},
+	})
+
+	assert.NoError(t, limiter.Wait(context.Background(), "FilterLogs"))
+	assert.NoError(t, limiter.Wait(context.Background(), "BlockNumber"))
+	assert.InDelta(t, 24, limiter.Usage().Available, 5)
+
+	// Calls costing more than the burst go through once the budget is full
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(context.Background(), "TraceBlock"))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Less(t, limiter.Usage().Available, float64(0))
+
+	// Callers giving up get their budget back
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	err := limiter.Wait(ctx, "FilterLogs")
+	assert.ErrorContains(t, err, utils.ErrEVMRateLimited)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	usage := limiter.Usage()
+	assert.Equal(t, uint64(3), usage.Requests)
+	assert.Equal(t, float64(326), usage.Units)
+	assert.Equal(t, map[string]uint64{"FilterLogs": 1, "BlockNumber": 1, "TraceBlock": 1}, usage.Methods)
+
+	// Without a rate nothing is limited, but usage is still counted
+	unlimited := evm.NewRateLimiter(evm.RateLimitConfig{})
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, unlimited.Wait(context.Background(), "ChainID"))
+	}
+	assert.Equal(t, uint64(1000), unlimited.Usage().Requests)
+	assert.Equal(t, uint64(0), unlimited.Usage().Throttled)
+}
+
+// TestRateLimitedClientFactory_DialContext tests that clients of the same endpoint share its budget.
+// go test -v -cover ./pkg/evm -run TestRateLimitedClientFactory_DialContext
+func TestRateLimitedClientFactory_DialContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), gomock.Any()).Return(mockClient, nil).Times(3)
+
+	factory := &evm.RateLimitedClientFactory{
+		Factory: mockClientFactory,
+		Endpoints: map[string]evm.RateLimitConfig{
+			testPrimaryURL: {Rate: 1000, Weights: map[string]float64{"FilterLogs": 10}},
+		},
+	}
+
+	first, err := factory.DialContext(context.Background(), testPrimaryURL)
+	assert.NoError(t, err)
+	second, err := factory.DialContext(context.Background(), testPrimaryURL)
+	assert.NoError(t, err)
+	backup, err := factory.DialContext(context.Background(), testBackupURL)
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().FilterLogs(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(100), nil).Times(2)
+	mockClient.EXPECT().BalanceAt(gomock.Any(), gomock.Any(), nil).Return(big.NewInt(7), nil)
+
+	_, err = first.FilterLogs(context.Background(), ethereum.FilterQuery{})
+	assert.NoError(t, err)
+	_, err = second.BlockNumber(context.Background())
+	assert.NoError(t, err)
+	_, err = backup.BlockNumber(context.Background())
+	assert.NoError(t, err)
+	_, err = backup.BalanceAt(context.Background(), testEventFromAddr, nil)
+	assert.NoError(t, err)
+
+	// Usage is reported by endpoint
+	usage := factory.Usage()
+	assert.Len(t, usage, 2)
+	assert.Equal(t, uint64(2), usage[testPrimaryURL].Requests)
+	assert.Equal(t, float64(11), usage[testPrimaryURL].Units)
+	assert.Equal(t, uint64(2), usage[testBackupURL].Requests)
+	assert.Equal(t, map[string]uint64{"BlockNumber": 1, "BalanceAt": 1}, usage[testBackupURL].Methods)
+	assert.Equal(t, usage[testPrimaryURL].Methods, first.(*evm.RateLimitedClient).Usage().Methods)
+}
+
+// TestRateLimitedClient_RawCalls tests that batched and raw JSON-RPC requests spend the budget too.
+// go test -v -cover ./pkg/evm -run TestRateLimitedClient_RawCalls
+func TestRateLimitedClient_RawCalls(t *testing.T) {
+	server := newCountingRPCServer(t, func(req testRPCRequest) (interface{}, *testRPCError) {
+		if req.Method == "eth_createAccessList" {
+			return map[string]interface{}{"accessList": []interface{}{}, "gasUsed": "0x5208"}, nil
+		}
+		return "0x10", nil
+	})
+	factory := &evm.RateLimitedClientFactory{
+		Endpoints: map[string]evm.RateLimitConfig{server.URL: {Rate: 20, Burst: 2}},
+	}
+
+	// Every call of a batch spends budget before the batch is sent
+	dialed, err := factory.DialContext(context.Background(), server.URL)
+	assert.NoError(t, err)
+	client, err := evm.NewBatchingClient(dialed, evm.BatchingConfig{Window: 20 * time.Millisecond})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			block, err := client.BlockNumber(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(16), block)
+		}()
+	}
+	wg.Wait()
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Equal(t, int32(1), server.batches.Load())
+
+	usage := factory.Usage()[server.URL]
+	assert.Equal(t, uint64(3), usage.Requests)
+	assert.Equal(t, uint64(1), usage.Throttled)
+	assert.Equal(t, map[string]uint64{"eth_blockNumber": 3}, usage.Methods)
+
+	// Methods ethclient lacks are sent raw, and wait for budget like the others
+	manager := evm.NewManager(server.URL, nil, factory, utils.Ethereum).(*evm.Manager)
+	assert.NoError(t, manager.Start(context.Background()))
+	defer manager.Stop(context.Background())
+
+	txType := utils.ContractCall
+	tx := evm.NewTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(0), &txType, nil, nil, nil,
+		0, nil, nil, 0, []byte{0x01})
+	_, gasUsed, err := manager.CreateAccessList(context.Background(), tx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(21000), gasUsed)
+
+	usage = factory.Usage()[server.URL]
+	assert.Equal(t, uint64(1), usage.Methods["eth_createAccessList"])
+	assert.Equal(t, uint64(2), usage.Throttled)
+}
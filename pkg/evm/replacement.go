@@ -0,0 +1,192 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// defaultReplacementBumpPercent matches the minimum price bump geth's transaction pool
+// requires to replace a pending transaction.
+const defaultReplacementBumpPercent = 10
+
+// SpeedUpTransaction replaces a pending transaction with a copy at the same nonce whose fees
+// are bumped by at least the replacement threshold, or raised to the current fee suggestion
+// when that is higher. It returns the hash of the replacement transaction.
+func (m *Manager) SpeedUpTransaction(ctx context.Context, txID string) (string, error) {
+	return m.replaceTransaction(ctx, txID, false)
+}
+
+// CancelTransaction replaces a pending transaction with a zero-value transfer from the sender
+// to itself at the same nonce, priced like SpeedUpTransaction. It returns the hash of the
+// replacement transaction.
+func (m *Manager) CancelTransaction(ctx context.Context, txID string) (string, error) {
+	return m.replaceTransaction(ctx, txID, true)
+}
+
+// ReplacedBy returns the hash of the transaction that replaced the given one through
+// SpeedUpTransaction or CancelTransaction.
+func (m *Manager) ReplacedBy(txID string) (string, bool) {
+	m.replacementsMu.Lock()
+	defer m.replacementsMu.Unlock()
+
+	replacement, ok := m.replacements[common.HexToHash(txID)]
+	if !ok {
+		return "", false
+	}
+	return replacement.Hex(), true
+}
+
+// replaceTransaction signs and broadcasts a replacement for a pending transaction.
+func (m *Manager) replaceTransaction(ctx context.Context, txID string, cancel bool) (string, error) {
+	if m.client == nil {
+		return "", utils.WrapError(utils.ErrClientNotStarted)
+	}
+
+	original, isPending, err := m.client.TransactionByHash(ctx, common.HexToHash(txID))
+	if err != nil {
+		return "", utils.WrapError(utils.ErrEVMFailedToRetrieveTransaction, err)
+	}
+	if !isPending {
+		return "", utils.WrapError(utils.ErrEVMTransactionNotPending)
+	}
+	if original.Type() == types.BlobTxType {
+		return "", utils.WrapError(utils.ErrEVMFailedToReplaceTransaction, errors.New("blob transactions cannot be replaced"))
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(original.ChainId()), original)
+	if err != nil {
+		return "", utils.WrapError(utils.ErrEVMFailedToReplaceTransaction, err)
+	}
+
+	replacement, err := m.newReplacement(ctx, original, sender, cancel)
+	if err != nil {
+		return "", utils.WrapError(utils.ErrEVMFailedToReplaceTransaction, err)
+	}
+
+	ethTx, err := m.signTransaction(replacement)
+	if err != nil {
+		return "", err
+	}
+
+	// A replacement signed by another key would not replace anything
+	replacementSender, err := types.Sender(types.LatestSignerForChainID(ethTx.ChainId()), ethTx)
+	if err != nil {
+		return "", utils.WrapError(utils.ErrEVMFailedToReplaceTransaction, err)
+	}
+	if replacementSender != sender {
+		return "", utils.WrapError(utils.ErrEVMFailedToReplaceTransaction,
+			fmt.Errorf("signer address %s does not match sender %s", replacementSender.Hex(), sender.Hex()))
+	}
+
+	if err := m.broadcast(ctx, ethTx); err != nil {
+		return "", err
+	}
+
+	m.replacementsMu.Lock()
+	if m.replacements == nil {
+		m.replacements = make(map[common.Hash]common.Hash)
+	}
+	m.replacements[original.Hash()] = ethTx.Hash()
+	m.replacementsMu.Unlock()
+
+	if m.tracker != nil {
+		m.tracker.replace(original.Hash(), replacement, ethTx)
+	}
+
+	return ethTx.Hash().Hex(), nil
+}
+
+// newReplacement builds the unsigned replacement of a pending transaction.
+func (m *Manager) newReplacement(
+	ctx context.Context,
+	original *types.Transaction,
+	sender common.Address,
+	cancel bool,
+) (utils.Transaction, error) {
+	from, err := NewAddress(sender.Hex(), m.network)
+	if err != nil {
+		return nil, err
+	}
+
+	txType := utils.Transfer
+	replacement := &BaseTransaction{
+		FromAddress: from,
+		TxType:      &txType,
+		TxPayload: map[string]interface{}{
+			"nonce":   original.Nonce(),
+			"chainId": original.ChainId(),
+		},
+	}
+
+	if cancel {
+		replacement.ToAddress = from
+		replacement.TxAmount = big.NewInt(0)
+		replacement.TxPayload["gasLimit"] = params.TxGas
+	} else {
+		switch {
+		case original.To() == nil:
+			txType = utils.Deploy
+		case len(original.Data()) > 0:
+			txType = utils.ContractCall
+		}
+		if original.To() != nil {
+			to, err := NewAddress(original.To().Hex(), m.network)
+			if err != nil {
+				return nil, err
+			}
+			replacement.ToAddress = to
+		}
+		replacement.TxAmount = original.Value()
+		replacement.TxPayload["gasLimit"] = original.Gas()
+		replacement.TxPayload["data"] = original.Data()
+		if len(original.AccessList()) > 0 {
+			replacement.TxPayload["accessList"] = original.AccessList()
+		}
+	}
+
+	suggestion, err := m.feeOracle.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := suggestion.Speed(m.feeSpeed)
+
+	// Keep the fee model of the original, as nodes compare fees of the same kind
+	if original.Type() == types.LegacyTxType || original.Type() == types.AccessListTxType {
+		replacement.TxPayload["gasPrice"] = maxBig(m.bumpFee(original.GasPrice()), current.GasPrice)
+		return replacement, nil
+	}
+
+	tipCap := maxBig(m.bumpFee(original.GasTipCap()), current.MaxPriorityFeePerGas)
+	feeCap := maxBig(m.bumpFee(original.GasFeeCap()), current.MaxFeePerGas)
+	replacement.TxPayload["maxPriorityFeePerGas"] = tipCap
+	replacement.TxPayload["maxFeePerGas"] = maxBig(feeCap, tipCap)
+	return replacement, nil
+}
+
+// bumpFee raises a fee by the replacement bump, rounding up so the result always clears
+// the node's threshold.
+func (m *Manager) bumpFee(fee *big.Int) *big.Int {
+	percent := m.replacementBumpPercent
+	if percent == 0 {
+		percent = defaultReplacementBumpPercent
+	}
+
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// maxBig returns the larger of a and b. A nil b is ignored.
+func maxBig(a, b *big.Int) *big.Int {
+	if b != nil && b.Cmp(a) > 0 {
+		return new(big.Int).Set(b)
+	}
+	return new(big.Int).Set(a)
+}
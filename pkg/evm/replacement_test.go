@@ -0,0 +1,173 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectFeeHistory makes the fee oracle suggest the given next base fee and tip.
+func expectFeeHistory(mockClient *mock_evm.MockClientInterface, baseFee, tip int64) {
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(&ethereum.FeeHistory{
+		BaseFee:      []*big.Int{big.NewInt(baseFee), big.NewInt(baseFee)},
+		Reward:       [][]*big.Int{{big.NewInt(tip), big.NewInt(tip), big.NewInt(tip)}},
+		GasUsedRatio: []float64{0.5},
+	}, nil)
+}
+
+// TestManager_SpeedUpTransaction tests that a pending transaction is resent with the same nonce and bumped fees.
+// go test -v -cover ./pkg/evm -run TestManager_SpeedUpTransaction
+func TestManager_SpeedUpTransaction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	privateKey := testKey(t)
+	manager, mockClient, _ := newTestManager(t, ctrl)
+
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	original, err := types.SignNewTx(privateKey, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     4,
+		GasTipCap: big.NewInt(2 * params.GWei),
+		GasFeeCap: big.NewInt(100 * params.GWei),
+		Gas:       60000,
+		To:        &to,
+		Value:     big.NewInt(1000),
+		Data:      []byte{0xa9, 0x05, 0x9c, 0xbb},
+	})
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), original.Hash()).Return(original, true, nil)
+	expectFeeHistory(mockClient, params.GWei, params.GWei)
+
+	var sent *types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = tx
+			return nil
+		})
+
+	// Act
+	hash, err := manager.SpeedUpTransaction(context.Background(), original.Hash().Hex())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sent.Hash().Hex(), hash)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), sent.Type())
+	assert.Equal(t, original.Nonce(), sent.Nonce())
+	assert.Equal(t, original.To(), sent.To())
+	assert.Equal(t, original.Value(), sent.Value())
+	assert.Equal(t, original.Data(), sent.Data())
+	assert.Equal(t, original.Gas(), sent.Gas())
+
+	// The fees are bumped by 10%, as the current suggestion is lower
+	assert.Equal(t, big.NewInt(2200000000), sent.GasTipCap())
+	assert.Equal(t, big.NewInt(110*params.GWei), sent.GasFeeCap())
+
+	replacedBy, ok := manager.ReplacedBy(original.Hash().Hex())
+	assert.True(t, ok)
+	assert.Equal(t, hash, replacedBy)
+}
+
+// TestManager_CancelTransaction tests that a pending transaction is replaced by a zero-value self-transfer.
+// go test -v -cover ./pkg/evm -run TestManager_CancelTransaction
+func TestManager_CancelTransaction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	privateKey := testKey(t)
+	manager, mockClient, _ := newTestManager(t, ctrl)
+	sender := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	original, err := types.SignNewTx(privateKey, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{
+		Nonce:    0,
+		GasPrice: big.NewInt(10 * params.GWei),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1000),
+	})
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), original.Hash()).Return(original, true, nil)
+	expectFeeHistory(mockClient, 20*params.GWei, params.GWei)
+
+	var sent *types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = tx
+			return nil
+		})
+
+	// Act
+	hash, err := manager.CancelTransaction(context.Background(), original.Hash().Hex())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, sent.Hash().Hex(), hash)
+	assert.Equal(t, uint8(types.LegacyTxType), sent.Type())
+	assert.Equal(t, uint64(0), sent.Nonce())
+	assert.Equal(t, sender, *sent.To())
+	assert.Equal(t, 0, sent.Value().Sign())
+	assert.Equal(t, params.TxGas, sent.Gas())
+	assert.Empty(t, sent.Data())
+
+	// The current suggestion (BaseFee + tip) is higher than the bumped gas price
+	assert.Equal(t, big.NewInt(21*params.GWei), sent.GasPrice())
+
+	replacedBy, ok := manager.ReplacedBy(original.Hash().Hex())
+	assert.True(t, ok)
+	assert.Equal(t, hash, replacedBy)
+}
+
+// TestManager_ReplaceTransaction_Errors tests that only pending transactions of the signer can be replaced.
+// go test -v -cover ./pkg/evm -run TestManager_ReplaceTransaction_Errors
+func TestManager_ReplaceTransaction_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	otherKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	manager, mockClient, _ := newTestManager(t, ctrl)
+
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	original, err := types.SignNewTx(otherKey, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{
+		Nonce:    1,
+		GasPrice: big.NewInt(params.GWei),
+		Gas:      21000,
+		To:       &to,
+		Value:    big.NewInt(1),
+	})
+	assert.NoError(t, err)
+
+	// The transaction was already mined
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), original.Hash()).Return(original, false, nil)
+	_, err = manager.SpeedUpTransaction(context.Background(), original.Hash().Hex())
+	assert.ErrorContains(t, err, utils.ErrEVMTransactionNotPending)
+
+	// The transaction is unknown to the node
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), original.Hash()).Return(nil, false, errors.New("not found"))
+	_, err = manager.CancelTransaction(context.Background(), original.Hash().Hex())
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToRetrieveTransaction)
+
+	// The transaction was sent by another account than the signer's
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), original.Hash()).Return(original, true, nil)
+	expectFeeHistory(mockClient, params.GWei, params.GWei)
+	_, err = manager.CancelTransaction(context.Background(), original.Hash().Hex())
+	assert.ErrorContains(t, err, utils.ErrEVMFailedToReplaceTransaction)
+	assert.ErrorContains(t, err, "does not match sender")
+
+	_, ok := manager.ReplacedBy(original.Hash().Hex())
+	assert.False(t, ok)
+}
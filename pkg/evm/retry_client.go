@@ -0,0 +1,546 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// RetryConfig configures how a RetryClient retries failed calls.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of times a call is made, 5 by default.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, 100ms by default. It doubles after
+	// every retry, and a random jitter of up to half of it is taken off every delay.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries, 5s by default.
+	MaxBackoff time.Duration
+}
+
+// retryableErrors are fragments of the errors providers return for failures that are worth
+// retrying, such as rate limits and overloaded nodes.
+var retryableErrors = []string{
+	"rate limit",
+	"too many requests",
+	"timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"temporarily unavailable",
+	"service unavailable",
+	"bad gateway",
+}
+
+// knownTransactionErrors are fragments of the errors nodes return when they already have a
+// transaction in their pool.
+var knownTransactionErrors = []string{
+	"already known",
+	"known transaction",
+	"already imported",
+}
+
+// RetryClient is a ClientInterface that retries the read calls failing with a retryable
+// error, as classified by IsRetryableError, with jittered exponential backoff. Retries stop
+// once the call's context is done or its deadline would pass before the next attempt.
+//
+// SendTransaction is not retried blindly. The same signed transaction is sent again only
+// after a retryable error, and a node reporting it already knows the transaction, or that
+// its nonce is too low because an earlier attempt got it mined, counts as a success.
+// Subscriptions go straight to the underlying client.
+type RetryClient struct {
+	ClientInterface
+	config RetryConfig
+}
+
+// NewRetryClient creates a new RetryClient instance retrying the calls of the client.
+func NewRetryClient(client ClientInterface, config RetryConfig) *RetryClient {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 5 * time.Second
+	}
+
+	return &RetryClient{ClientInterface: client, config: config}
+}
+
+// IsRetryableError reports whether err is a transient provider failure that may succeed
+// when retried: rate limits, gateway and availability HTTP errors, timeouts and dropped
+// connections. Reverts, missing results, cancellations and JSON-RPC errors other than
+// limit exceeded (-32005) are permanent, whatever their message says.
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ethereum.NotFound) || errors.Is(err, rpc.ErrClientQuit) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case 408, 425, 429, 500, 502, 503, 504:
+			return true
+		}
+		return false
+	}
+	// The node answered, so only its limit exceeded code is worth retrying, unless it reports
+	// an oversized log query, which GetLogs splits instead
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == -32005 && !isLogRangeError(err) // Limit exceeded
+	}
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	return containsAny(err, retryableErrors)
+}
+
+// ChainID retrieves the current chain ID.
+func (c *RetryClient) ChainID(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.ChainID(ctx)
+		return err
+	})
+	return result, err
+}
+
+// BlockByHash returns the given full block.
+func (c *RetryClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	var result *types.Block
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.BlockByHash(ctx, hash)
+		return err
+	})
+	return result, err
+}
+
+// BlockByNumber returns a block from the current canonical chain. If number is nil, the
+// latest known block is returned.
+func (c *RetryClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	var result *types.Block
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.BlockByNumber(ctx, number)
+		return err
+	})
+	return result, err
+}
+
+// BlockNumber returns the most recent block number.
+func (c *RetryClient) BlockNumber(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.BlockNumber(ctx)
+		return err
+	})
+	return result, err
+}
+
+// PeerCount returns the number of p2p peers as reported by the net_peerCount method.
+func (c *RetryClient) PeerCount(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.PeerCount(ctx)
+		return err
+	})
+	return result, err
+}
+
+// BlockReceipts returns the receipts of a given block number or hash.
+func (c *RetryClient) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var result []*types.Receipt
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.BlockReceipts(ctx, blockNrOrHash)
+		return err
+	})
+	return result, err
+}
+
+// NetworkID returns the network ID for this client.
+func (c *RetryClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.NetworkID(ctx)
+		return err
+	})
+	return result, err
+}
+
+// BalanceAt returns the wei balance of the given account.
+func (c *RetryClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result *big.Int
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.BalanceAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// BalanceAtHash returns the wei balance of the given account at the given block hash.
+func (c *RetryClient) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	var result *big.Int
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.BalanceAtHash(ctx, account, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// StorageAt returns the value of key in the contract storage of the given account.
+func (c *RetryClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.StorageAt(ctx, account, key, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// StorageAtHash returns the value of key in the contract storage of the given account at the
+// given block hash.
+func (c *RetryClient) StorageAtHash(ctx context.Context, account common.Address, key common.Hash, blockHash common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.StorageAtHash(ctx, account, key, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// CodeAt returns the contract code of the given account.
+func (c *RetryClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// CodeAtHash returns the contract code of the given account at the given block hash.
+func (c *RetryClient) CodeAtHash(ctx context.Context, account common.Address, blockHash common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.CodeAtHash(ctx, account, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// NonceAt returns the account nonce of the given account.
+func (c *RetryClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	var result uint64
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.NonceAt(ctx, account, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// NonceAtHash returns the account nonce of the given account at the given block hash.
+func (c *RetryClient) NonceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (uint64, error) {
+	var result uint64
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.NonceAtHash(ctx, account, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If number is
+// nil, the latest known header is returned.
+func (c *RetryClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var result *types.Header
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.HeaderByNumber(ctx, number)
+		return err
+	})
+	return result, err
+}
+
+// PendingBalanceAt returns the wei balance of the given account in the pending state.
+func (c *RetryClient) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	var result *big.Int
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.PendingBalanceAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+// PendingStorageAt returns the value of key in the contract storage of the given account in
+// the pending state.
+func (c *RetryClient) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.PendingStorageAt(ctx, account, key)
+		return err
+	})
+	return result, err
+}
+
+// PendingCodeAt returns the contract code of the given account in the pending state.
+func (c *RetryClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.PendingCodeAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+// PendingNonceAt returns the account nonce of the given account in the pending state.
+func (c *RetryClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var result uint64
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.PendingNonceAt(ctx, account)
+		return err
+	})
+	return result, err
+}
+
+// PendingTransactionCount returns the total number of transactions in the pending state.
+func (c *RetryClient) PendingTransactionCount(ctx context.Context) (uint, error) {
+	var result uint
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.PendingTransactionCount(ctx)
+		return err
+	})
+	return result, err
+}
+
+// CallContract executes a message call transaction without creating a transaction on the chain.
+func (c *RetryClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// CallContractAtHash executes a message call transaction at the given block hash.
+func (c *RetryClient) CallContractAtHash(ctx context.Context, msg ethereum.CallMsg, blockHash common.Hash) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.CallContractAtHash(ctx, msg, blockHash)
+		return err
+	})
+	return result, err
+}
+
+// PendingCallContract executes a message call transaction against the pending state.
+func (c *RetryClient) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	var result []byte
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.PendingCallContract(ctx, msg)
+		return err
+	})
+	return result, err
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price.
+func (c *RetryClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.SuggestGasPrice(ctx)
+		return err
+	})
+	return result, err
+}
+
+// SuggestGasTipCap retrieves the currently suggested gas tip cap.
+func (c *RetryClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var result *big.Int
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.SuggestGasTipCap(ctx)
+		return err
+	})
+	return result, err
+}
+
+// FeeHistory retrieves the fee market history.
+func (c *RetryClient) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	var result *ethereum.FeeHistory
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+		return err
+	})
+	return result, err
+}
+
+// EstimateGas estimates the gas needed to execute a specific transaction.
+func (c *RetryClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	var result uint64
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.EstimateGas(ctx, msg)
+		return err
+	})
+	return result, err
+}
+
+// TransactionByHash returns the transaction with the given hash.
+func (c *RetryClient) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	var result *types.Transaction
+	var isPending bool
+	err := c.retry(ctx, func() (err error) {
+		result, isPending, err = c.ClientInterface.TransactionByHash(ctx, txHash)
+		return err
+	})
+	return result, isPending, err
+}
+
+// TransactionReceipt returns the receipt of a transaction by transaction hash.
+func (c *RetryClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var result *types.Receipt
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return result, err
+}
+
+// FilterLogs executes a filter query.
+func (c *RetryClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var result []types.Log
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.ClientInterface.FilterLogs(ctx, q)
+		return err
+	})
+	return result, err
+}
+
+// SendTransaction injects a signed transaction into the pending pool for execution. After a
+// retryable error the same transaction is sent again, since the node may or may not have
+// received it. A node that already knows the transaction, or rejects a resent one with a
+// nonce too low while it knows its hash, means an earlier attempt went through.
+func (c *RetryClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	backoff := c.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.ClientInterface.SendTransaction(ctx, tx)
+		switch {
+		case err == nil, containsAny(err, knownTransactionErrors):
+			return nil
+		case attempt > 1 && strings.Contains(err.Error(), "nonce too low"):
+			if _, _, lookupErr := c.ClientInterface.TransactionByHash(ctx, tx.Hash()); lookupErr == nil {
+				return nil
+			}
+			return err
+		case attempt >= c.config.MaxAttempts || !IsRetryableError(err) || !c.wait(ctx, backoff):
+			return err
+		}
+		backoff = min(backoff*2, c.config.MaxBackoff)
+	}
+}
+
+// CallContext sends a raw JSON-RPC request, retrying it like the other calls.
+func (c *RetryClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	return c.retry(ctx, func() error {
+		return caller.CallContext(ctx, result, method, args...)
+	})
+}
+
+// BatchCallContext sends raw JSON-RPC requests in one batch, retrying the batch when the
+// request itself fails. Errors of single requests in it are left to their callers.
+func (c *RetryClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	caller := rpcCaller(c.ClientInterface)
+	if caller == nil {
+		return errRPCClientUnavailable
+	}
+	return c.retry(ctx, func() error {
+		return caller.BatchCallContext(ctx, b)
+	})
+}
+
+// retry runs call until it succeeds, fails with an error that is not retryable or runs out
+// of attempts, waiting with exponential backoff between attempts.
+func (c *RetryClient) retry(ctx context.Context, call func() error) error {
+	backoff := c.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= c.config.MaxAttempts || !IsRetryableError(err) || !c.wait(ctx, backoff) {
+			return err
+		}
+		backoff = min(backoff*2, c.config.MaxBackoff)
+	}
+}
+
+// wait sleeps for the backoff less a random jitter of up to half of it. It returns false,
+// without sleeping, when the context deadline would pass before the next attempt, or as soon
+// as the context is done.
+func (c *RetryClient) wait(ctx context.Context, backoff time.Duration) bool {
+	delay := backoff - time.Duration(rand.Int63n(int64(backoff)/2+1))
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// containsAny reports whether the message of err contains any of the fragments.
+func containsAny(err error, fragments []string) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range fragments {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryClientFactory is a ClientFactory that wraps the clients of another factory in
+// RetryClients.
+type RetryClientFactory struct {
+	// Factory dials the underlying clients, an EthClientFactory when nil.
+	Factory ClientFactory
+	// Config configures how the clients retry calls.
+	Config RetryConfig
+}
+
+// DialContext dials a new client and wraps it in a RetryClient.
+func (f *RetryClientFactory) DialContext(ctx context.Context, url string) (ClientInterface, error) {
+	factory := f.Factory
+	if factory == nil {
+		factory = &EthClientFactory{}
+	}
+
+	client, err := factory.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return NewRetryClient(client, f.Config), nil
+}
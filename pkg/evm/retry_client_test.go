@@ -0,0 +1,223 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRPCCodeError is a JSON-RPC error with a code.
+type testRPCCodeError struct {
+	code int
+	msg  string
+}
+
+func (e testRPCCodeError) Error() string  { return e.msg }
+func (e testRPCCodeError) ErrorCode() int { return e.code }
+
+// testRPCDataError is a JSON-RPC error carrying data, with a message that looks transient.
+type testRPCDataError struct{}
+
+func (testRPCDataError) Error() string          { return "request timed out" }
+func (testRPCDataError) ErrorData() interface{} { return "0x" }
+
+// testTimeoutError is a network timeout.
+type testTimeoutError struct{}
+
+func (testTimeoutError) Error() string   { return "i/o timeout" }
+func (testTimeoutError) Timeout() bool   { return true }
+func (testTimeoutError) Temporary() bool { return true }
+
+// newRetryClient returns a retry client over a mock client, with backoffs short enough for tests.
+func newRetryClient(ctrl *gomock.Controller) (*evm.RetryClient, *mock_evm.MockClientInterface) {
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	return evm.NewRetryClient(mockClient, evm.RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+	}), mockClient
+}
+
+// TestIsRetryableError tests classifying provider errors as retryable or permanent.
+// go test -v -cover ./pkg/evm -run TestIsRetryableError
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"rate limited", rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, true},
+		{"bad gateway", fmt.Errorf("wrapped: %w", rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}), true},
+		{"service unavailable", rpc.HTTPError{StatusCode: 503}, true},
+		{"bad request", rpc.HTTPError{StatusCode: 400}, false},
+		{"unauthorized", rpc.HTTPError{StatusCode: 401}, false},
+		{"limit exceeded", testRPCCodeError{code: -32005, msg: "limit exceeded"}, true},
+		{"log range exceeded", testRPCCodeError{code: -32005, msg: "query returned more than 10000 results"}, false},
+		{"rate limit message", errors.New("Your app has exceeded its compute units per second capacity, rate limit reached"), true},
+		{"rpc error mentioning a timeout", testRPCCodeError{code: -32000, msg: "execution timeout"}, false},
+		{"rpc error with data", testRPCDataError{}, false},
+		{"revert", testRPCCodeError{code: 3, msg: "execution reverted"}, false},
+		{"invalid params", testRPCCodeError{code: -32602, msg: "invalid argument 0"}, false},
+		{"network timeout", &url.Error{Op: "Post", URL: "http://localhost", Err: testTimeoutError{}}, true},
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"connection refused", syscall.ECONNREFUSED, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"not found", ethereum.NotFound, false},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", fmt.Errorf("call: %w", context.DeadlineExceeded), false},
+		{"client closed", rpc.ErrClientQuit, false},
+		{"insufficient funds", errors.New("insufficient funds for gas * price + value"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.retryable, evm.IsRetryableError(tt.err))
+		})
+	}
+}
+
+// TestRetryClient_Reads tests that reads are retried on transient errors only.
+// go test -v -cover ./pkg/evm -run TestRetryClient_Reads
+func TestRetryClient_Reads(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client, mockClient := newRetryClient(ctrl)
+	account := common.HexToAddress(generateRandomAddress().String())
+
+	// Transient errors are retried until the call succeeds
+	gomock.InOrder(
+		mockClient.EXPECT().BalanceAt(gomock.Any(), account, nil).Return(nil, rpc.HTTPError{StatusCode: 429}),
+		mockClient.EXPECT().BalanceAt(gomock.Any(), account, nil).Return(nil, &url.Error{Op: "Post", Err: testTimeoutError{}}),
+		mockClient.EXPECT().BalanceAt(gomock.Any(), account, nil).Return(big.NewInt(42), nil),
+	)
+	balance, err := client.BalanceAt(context.Background(), account, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(42), balance)
+
+	// Until the attempts run out
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(0), rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}).Times(3)
+	_, err = client.BlockNumber(context.Background())
+	assert.ErrorContains(t, err, "502")
+
+	// Permanent errors are returned at once
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).Return(nil, testRPCCodeError{code: 3, msg: "execution reverted"})
+	_, err = client.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	assert.ErrorContains(t, err, "execution reverted")
+
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), gomock.Any()).Return(nil, ethereum.NotFound)
+	_, err = client.TransactionReceipt(context.Background(), common.HexToHash("0x01"))
+	assert.ErrorIs(t, err, ethereum.NotFound)
+
+	// Subscriptions go straight to the underlying client
+	mockClient.EXPECT().SubscribeNewHead(gomock.Any(), gomock.Any()).Return(nil, rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"})
+	_, err = client.SubscribeNewHead(context.Background(), make(chan *types.Header))
+	assert.Error(t, err)
+
+	// Retries do not outlive the context deadline
+	slow := evm.NewRetryClient(mockClient, evm.RetryConfig{InitialBackoff: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	mockClient.EXPECT().ChainID(gomock.Any()).Return(nil, rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"})
+
+	start := time.Now()
+	_, err = slow.ChainID(ctx)
+	assert.ErrorContains(t, err, "503")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+// TestRetryClient_SendTransaction tests that sends are retried only when an earlier attempt cannot have gone through.
+// go test -v -cover ./pkg/evm -run TestRetryClient_SendTransaction
+func TestRetryClient_SendTransaction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client, mockClient := newRetryClient(ctrl)
+	tx, _ := generateSignedTransaction(t, common.HexToAddress(generateRandomAddress().String()), big.NewInt(1))
+	connectionReset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+	// A node that already has the transaction received an earlier attempt
+	gomock.InOrder(
+		mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(connectionReset),
+		mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("already known")),
+	)
+	assert.NoError(t, client.SendTransaction(context.Background(), tx))
+
+	// As does a nonce too low for a transaction the node knows
+	gomock.InOrder(
+		mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(connectionReset),
+		mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("nonce too low")),
+		mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(tx, false, nil),
+	)
+	assert.NoError(t, client.SendTransaction(context.Background(), tx))
+
+	// But not for a transaction it does not know
+	gomock.InOrder(
+		mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(connectionReset),
+		mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("nonce too low")),
+		mockClient.EXPECT().TransactionByHash(gomock.Any(), tx.Hash()).Return(nil, false, ethereum.NotFound),
+	)
+	assert.ErrorContains(t, client.SendTransaction(context.Background(), tx), "nonce too low")
+
+	// A nonce too low on the first attempt is returned as is
+	mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("nonce too low"))
+	assert.ErrorContains(t, client.SendTransaction(context.Background(), tx), "nonce too low")
+
+	// Rejections are never retried
+	mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(errors.New("insufficient funds for gas * price + value"))
+	assert.ErrorContains(t, client.SendTransaction(context.Background(), tx), "insufficient funds")
+
+	// Transient errors are retried until the attempts run out
+	mockClient.EXPECT().SendTransaction(gomock.Any(), tx).Return(rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}).Times(3)
+	assert.Error(t, client.SendTransaction(context.Background(), tx))
+}
+
+// TestRetryClientFactory_DialContext tests wrapping the clients of another factory.
+// go test -v -cover ./pkg/evm -run TestRetryClientFactory_DialContext
+func TestRetryClientFactory_DialContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	factory := &evm.RetryClientFactory{Factory: mockClientFactory, Config: evm.RetryConfig{InitialBackoff: time.Millisecond}}
+
+	// Dial failures are returned
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(nil, errors.New("dial failed"))
+	_, err := factory.DialContext(context.Background(), "http://localhost:8545")
+	assert.ErrorContains(t, err, "dial failed")
+
+	// Managers use retry clients as a drop-in
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+	manager := evm.NewManager("http://localhost:8545", nil, factory, utils.Ethereum)
+	assert.NoError(t, manager.Start(context.Background()))
+
+	gomock.InOrder(
+		mockClient.EXPECT().BalanceAt(gomock.Any(), gomock.Any(), nil).Return(nil, rpc.HTTPError{StatusCode: 429}),
+		mockClient.EXPECT().BalanceAt(gomock.Any(), gomock.Any(), nil).Return(big.NewInt(7), nil),
+	)
+	balance, err := manager.GetBalance(context.Background(), generateRandomAddress(), utils.Token{Type: utils.Native})
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(7), balance)
+
+	mockClient.EXPECT().Close()
+	manager.Stop(context.Background())
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
 	"github.com/mselser95/blockchain/pkg/utils"
 )
 
@@ -41,17 +43,55 @@ func (pks *PrivateKeySigner) SignTransaction(tx utils.Transaction) (utils.Transa
 
 	// Prepare the transaction parameters from the utils.Transaction
 	nonce := tx.Payload()["nonce"].(uint64)
-	gasPrice := tx.Payload()["gasPrice"].(*big.Int)
 	gasLimit := tx.Payload()["gasLimit"].(uint64)
 	chainId := tx.Payload()["chainId"].(*big.Int)
-	to := common.HexToAddress(tx.To().String())
+	var to *common.Address // Nil for contract deployments
+	if tx.To() != nil && tx.To().String() != "" {
+		toAddress := common.HexToAddress(tx.To().String())
+		to = &toAddress
+	}
 	value := tx.Amount()
-	data := tx.Payload()["data"].([]byte)
+	data, _ := tx.Payload()["data"].([]byte)
+	accessList, hasAccessList := tx.Payload()["accessList"].(types.AccessList)
 
-	// Create the transaction object
-	signedTx := types.NewTx(&types.LegacyTx{
-		Nonce: nonce, GasPrice: gasPrice, Gas: gasLimit, To: &to, Value: value, Data: data,
-	})
+	// Create the transaction object, using dynamic fees when the payload carries them
+	var txData types.TxData
+	switch gasFeeCap, isDynamic := tx.Payload()["maxFeePerGas"].(*big.Int); {
+	case tx.Payload()["blobs"] != nil:
+		blobTx, err := newBlobTx(tx, *to, data, accessList)
+		if err != nil {
+			return nil, utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+		}
+		txData = blobTx
+	case isDynamic:
+		txData = &types.DynamicFeeTx{
+			ChainID:    chainId,
+			Nonce:      nonce,
+			GasTipCap:  tx.Payload()["maxPriorityFeePerGas"].(*big.Int),
+			GasFeeCap:  gasFeeCap,
+			Gas:        gasLimit,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		}
+	case hasAccessList:
+		txData = &types.AccessListTx{
+			ChainID:    chainId,
+			Nonce:      nonce,
+			GasPrice:   tx.Payload()["gasPrice"].(*big.Int),
+			Gas:        gasLimit,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		}
+	default:
+		txData = &types.LegacyTx{
+			Nonce: nonce, GasPrice: tx.Payload()["gasPrice"].(*big.Int), Gas: gasLimit, To: to, Value: value, Data: data,
+		}
+	}
+	signedTx := types.NewTx(txData)
 
 	// Sign the transaction using the private key
 	txSigner := types.LatestSignerForChainID(chainId)
@@ -72,3 +112,43 @@ func (pks *PrivateKeySigner) SignTransaction(tx utils.Transaction) (utils.Transa
 
 	return tx, nil
 }
+
+// newBlobTx builds an EIP-4844 transaction, computing the KZG commitments and proofs
+// for the blobs in the payload.
+func newBlobTx(tx utils.Transaction, to common.Address, data []byte, accessList types.AccessList) (*types.BlobTx, error) {
+	payload := tx.Payload()
+
+	sidecar, err := newBlobSidecar(payload["blobs"].([]kzg4844.Blob))
+	if err != nil {
+		return nil, err
+	}
+
+	blobTx := &types.BlobTx{
+		Nonce:      payload["nonce"].(uint64),
+		Gas:        payload["gasLimit"].(uint64),
+		To:         to,
+		Data:       data,
+		AccessList: accessList,
+		BlobHashes: sidecar.BlobHashes(),
+		Sidecar:    sidecar,
+	}
+
+	fields := []struct {
+		name  string
+		value *big.Int
+		dest  **uint256.Int
+	}{
+		{"chainId", payload["chainId"].(*big.Int), &blobTx.ChainID},
+		{"maxPriorityFeePerGas", payload["maxPriorityFeePerGas"].(*big.Int), &blobTx.GasTipCap},
+		{"maxFeePerGas", payload["maxFeePerGas"].(*big.Int), &blobTx.GasFeeCap},
+		{"maxFeePerBlobGas", payload["maxFeePerBlobGas"].(*big.Int), &blobTx.BlobFeeCap},
+		{"amount", tx.Amount(), &blobTx.Value},
+	}
+	for _, field := range fields {
+		if *field.dest, err = toUint256(field.name, field.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return blobTx, nil
+}
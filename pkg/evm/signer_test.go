@@ -2,7 +2,9 @@ package evm_test
 
 import (
 	"fmt"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"math/big"
 	"testing"
 
@@ -135,3 +137,149 @@ func TestPrivateKeySigner_SignTransaction_MissingPayloadFields(t *testing.T) {
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, utils.ErrEVMInvalidTransaction)
 }
+
+func TestPrivateKeySigner_SignTransaction_DynamicFee(t *testing.T) {
+	// Generate a new random private key
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	// Convert the private key to a hexadecimal string
+	privateKeyHex := fmt.Sprintf("%x", crypto.FromECDSA(privateKey))
+	signer, err := evm.NewPrivateKeySigner(privateKeyHex)
+	assert.NoError(t, err)
+
+	from, err := evm.NewAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+	to := generateRandomAddress()
+
+	txType := utils.Transfer
+	tx := evm.NewDynamicFeeTransaction(
+		nil, from, to, big.NewInt(1000000000000000000),
+		&txType, nil, nil, nil,
+		21000, big.NewInt(1000000000), big.NewInt(30000000000), big.NewInt(8453), 7, nil,
+	)
+
+	signedTx, err := signer.SignTransaction(tx)
+	assert.NoError(t, err)
+
+	// Check that a dynamic fee transaction was produced with the payload fees
+	storedSignedTx, ok := signedTx.Payload()["signedTransaction"].(*types.Transaction)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), storedSignedTx.Type())
+	assert.Equal(t, big.NewInt(1000000000), storedSignedTx.GasTipCap())
+	assert.Equal(t, big.NewInt(30000000000), storedSignedTx.GasFeeCap())
+	assert.Equal(t, big.NewInt(8453), storedSignedTx.ChainId())
+	assert.Equal(t, uint64(7), storedSignedTx.Nonce())
+
+	sender, err := types.Sender(types.LatestSignerForChainID(storedSignedTx.ChainId()), storedSignedTx)
+	assert.NoError(t, err)
+	assert.Equal(t, from.String(), sender.Hex())
+}
+
+func TestPrivateKeySigner_SignTransaction_AccessList(t *testing.T) {
+	// Generate a new random private key
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	// Convert the private key to a hexadecimal string
+	privateKeyHex := fmt.Sprintf("%x", crypto.FromECDSA(privateKey))
+	signer, err := evm.NewPrivateKeySigner(privateKeyHex)
+	assert.NoError(t, err)
+
+	from, err := evm.NewAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+	to := generateRandomAddress()
+	accessList := types.AccessList{{
+		Address:     common.HexToAddress(to.String()),
+		StorageKeys: []common.Hash{common.HexToHash("0x01")},
+	}}
+
+	txType := utils.ContractCall
+
+	// A legacy-priced transaction with an access list becomes an EIP-2930 transaction
+	legacyTx := evm.NewTransaction(nil, from, to, big.NewInt(1), &txType, nil, nil, nil,
+		50000, big.NewInt(20000000000), big.NewInt(1), 1, []byte{0x1})
+	legacyTx.SetPayload("accessList", accessList)
+
+	signedTx, err := signer.SignTransaction(legacyTx)
+	assert.NoError(t, err)
+	storedSignedTx := signedTx.Payload()["signedTransaction"].(*types.Transaction)
+	assert.Equal(t, uint8(types.AccessListTxType), storedSignedTx.Type())
+	assert.Equal(t, accessList, storedSignedTx.AccessList())
+
+	// A dynamic fee transaction carries the access list as well
+	dynamicTx := evm.NewDynamicFeeTransaction(nil, from, to, big.NewInt(1), &txType, nil, nil, nil,
+		50000, big.NewInt(1), big.NewInt(100), big.NewInt(1), 2, []byte{0x1})
+	dynamicTx.SetPayload("accessList", accessList)
+
+	signedTx, err = signer.SignTransaction(dynamicTx)
+	assert.NoError(t, err)
+	storedSignedTx = signedTx.Payload()["signedTransaction"].(*types.Transaction)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), storedSignedTx.Type())
+	assert.Equal(t, accessList, storedSignedTx.AccessList())
+}
+
+func TestPrivateKeySigner_SignTransaction_Blob(t *testing.T) {
+	// Generate a new random private key
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	// Convert the private key to a hexadecimal string
+	privateKeyHex := fmt.Sprintf("%x", crypto.FromECDSA(privateKey))
+	signer, err := evm.NewPrivateKeySigner(privateKeyHex)
+	assert.NoError(t, err)
+
+	from, err := evm.NewAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), utils.Ethereum)
+	assert.NoError(t, err)
+	to := generateRandomAddress()
+
+	blobs, err := evm.EncodeBlobs([]byte("rollup batch"))
+	assert.NoError(t, err)
+
+	txType := utils.Transfer
+	tx := evm.NewDynamicFeeTransaction(nil, from, to, big.NewInt(1), &txType, nil, nil, nil,
+		21000, big.NewInt(1000000000), big.NewInt(30000000000), big.NewInt(1), 4, nil)
+	tx.SetPayload("blobs", blobs)
+	tx.SetPayload("maxFeePerBlobGas", big.NewInt(1000))
+
+	signedTx, err := signer.SignTransaction(tx)
+	assert.NoError(t, err)
+
+	// Check that a blob transaction with a valid sidecar was produced
+	storedSignedTx := signedTx.Payload()["signedTransaction"].(*types.Transaction)
+	assert.Equal(t, uint8(types.BlobTxType), storedSignedTx.Type())
+	assert.Equal(t, big.NewInt(1000), storedSignedTx.BlobGasFeeCap())
+	assert.Len(t, storedSignedTx.BlobHashes(), 1)
+	assert.NotNil(t, storedSignedTx.BlobTxSidecar())
+	assert.Equal(t, storedSignedTx.BlobHashes(), storedSignedTx.BlobTxSidecar().BlobHashes())
+	assert.Equal(t, blobs, storedSignedTx.BlobTxSidecar().Blobs)
+	assert.NoError(t, kzg4844.VerifyBlobProof(&blobs[0], storedSignedTx.BlobTxSidecar().Commitments[0],
+		storedSignedTx.BlobTxSidecar().Proofs[0]))
+
+	sender, err := types.Sender(types.LatestSignerForChainID(storedSignedTx.ChainId()), storedSignedTx)
+	assert.NoError(t, err)
+	assert.Equal(t, from.String(), sender.Hex())
+}
+
+func TestPrivateKeySigner_SignTransaction_BlobMissingBlobFee(t *testing.T) {
+	// Generate a new random private key
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	// Convert the private key to a hexadecimal string
+	privateKeyHex := fmt.Sprintf("%x", crypto.FromECDSA(privateKey))
+	signer, err := evm.NewPrivateKeySigner(privateKeyHex)
+	assert.NoError(t, err)
+
+	blobs, err := evm.EncodeBlobs([]byte("rollup batch"))
+	assert.NoError(t, err)
+
+	txType := utils.Transfer
+	tx := evm.NewDynamicFeeTransaction(nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(1), &txType,
+		nil, nil, nil, 21000, big.NewInt(1000000000), big.NewInt(30000000000), big.NewInt(1), 4, nil)
+	tx.SetPayload("blobs", blobs)
+
+	_, err = signer.SignTransaction(tx)
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidTransaction)
+	assert.ErrorContains(t, err, "missing payload 'maxFeePerBlobGas'")
+}
@@ -0,0 +1,70 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// tokenStandards names the token types implemented by contracts on EVM chains.
+var tokenStandards = map[utils.TokenType]string{
+	utils.ERC20:   "ERC20",
+	utils.ERC721:  "ERC721",
+	utils.ERC1155: "ERC1155",
+}
+
+// callToken calls a read-only method of a token contract of the given standard and
+// unpacks its output into out.
+func (m *Manager) callToken(
+	ctx context.Context,
+	token utils.Token,
+	standard utils.TokenType,
+	abiJSON string,
+	method string,
+	out interface{},
+	args ...interface{},
+) error {
+	if m.client == nil {
+		return utils.WrapError(utils.ErrClientNotStarted)
+	}
+	contract, err := tokenAddress(token, standard)
+	if err != nil {
+		return err
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s ABI: %w", tokenStandards[standard], err)
+	}
+	data, err := parsedABI.Pack(method, args...)
+	if err != nil {
+		return utils.WrapError(utils.ErrEVMInvalidTransaction, err)
+	}
+
+	output, err := m.client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return wrapCallError(utils.ErrEVMFailedToReadToken, err)
+	}
+	if err := parsedABI.UnpackIntoInterface(out, method, output); err != nil {
+		return utils.WrapError(utils.ErrEVMFailedToReadToken, fmt.Errorf("failed to unpack %s: %w", method, err))
+	}
+	return nil
+}
+
+// tokenAddress returns the contract address of a token of the given standard.
+func tokenAddress(token utils.Token, standard utils.TokenType) (common.Address, error) {
+	if token.Type != standard {
+		return common.Address{}, utils.WrapError(utils.ErrEVMInvalidToken,
+			fmt.Errorf("not an %s token", tokenStandards[standard]))
+	}
+	if token.Address == nil || *token.Address == nil || !common.IsHexAddress((*token.Address).String()) {
+		return common.Address{}, utils.WrapError(utils.ErrEVMInvalidToken, errors.New("invalid token address"))
+	}
+	return common.HexToAddress((*token.Address).String()), nil
+}
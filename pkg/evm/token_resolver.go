@@ -0,0 +1,314 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// tokenListNetworks maps the chain IDs used in token lists to the networks they belong to.
+var tokenListNetworks = map[int64]utils.Blockchain{
+	1:     utils.Ethereum,
+	10:    utils.Optimism,
+	56:    utils.Bsc,
+	137:   utils.Polygon,
+	8453:  utils.Base,
+	42161: utils.Arbitrum,
+	43114: utils.Avalanche,
+	81457: utils.Blast,
+}
+
+// TokenStore persists resolved token metadata so that it survives restarts.
+type TokenStore interface {
+	// Get returns the token stored for the contract address on the network, if any.
+	Get(network utils.Blockchain, address common.Address) (utils.Token, bool, error)
+
+	// Put stores a token for the network, keyed by its contract address.
+	Put(network utils.Blockchain, token utils.Token) error
+}
+
+// tokenKey identifies a token contract on a network.
+type tokenKey struct {
+	network utils.Blockchain
+	address common.Address
+}
+
+// TokenResolver resolves ERC20 token metadata by network and contract address. Resolved
+// tokens are cached in memory and, when a TokenStore is configured, persisted. It is safe
+// for concurrent use and can be shared by the Managers of several networks.
+type TokenResolver struct {
+	mu     sync.RWMutex
+	tokens map[tokenKey]utils.Token
+	store  TokenStore
+}
+
+// NewTokenResolver creates a new TokenResolver instance backed by the store, or by memory
+// only when the store is nil.
+func NewTokenResolver(store TokenStore) *TokenResolver {
+	return &TokenResolver{
+		tokens: make(map[tokenKey]utils.Token),
+		store:  store,
+	}
+}
+
+// Lookup returns the known token at the contract address on the network without querying
+// the chain.
+func (r *TokenResolver) Lookup(network utils.Blockchain, address common.Address) (utils.Token, bool, error) {
+	key := tokenKey{network: network, address: address}
+
+	r.mu.RLock()
+	token, ok := r.tokens[key]
+	r.mu.RUnlock()
+	if ok || r.store == nil {
+		return token, ok, nil
+	}
+
+	token, ok, err := r.store.Get(network, address)
+	if err != nil || !ok {
+		return utils.Token{}, false, err
+	}
+
+	r.mu.Lock()
+	r.tokens[key] = token
+	r.mu.Unlock()
+	return token, true, nil
+}
+
+// Add caches a token for the network and persists it to the store.
+func (r *TokenResolver) Add(network utils.Blockchain, token utils.Token) error {
+	address, err := tokenAddress(token, utils.ERC20)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.tokens[tokenKey{network: network, address: address}] = token
+	r.mu.Unlock()
+
+	if r.store == nil {
+		return nil
+	}
+	return r.store.Put(network, token)
+}
+
+// tokenList is a token list in the Uniswap token list format.
+type tokenList struct {
+	Tokens []struct {
+		ChainID  int64  `json:"chainId"`
+		Address  string `json:"address"`
+		Name     string `json:"name"`
+		Symbol   string `json:"symbol"`
+		Decimals int    `json:"decimals"`
+	} `json:"tokens"`
+}
+
+// LoadTokenList seeds the in-memory cache from a token list in the Uniswap token list format.
+// Tokens of chains that map to no supported network are skipped. A list with an invalid
+// entry is rejected as a whole, leaving the cache untouched.
+func (r *TokenResolver) LoadTokenList(reader io.Reader) error {
+	var list tokenList
+	if err := json.NewDecoder(reader).Decode(&list); err != nil {
+		return utils.WrapError(utils.ErrEVMInvalidTokenList, err)
+	}
+
+	keys := make([]tokenKey, 0, len(list.Tokens))
+	tokens := make([]utils.Token, 0, len(list.Tokens))
+	for _, entry := range list.Tokens {
+		network, ok := tokenListNetworks[entry.ChainID]
+		if !ok {
+			continue
+		}
+		if !common.IsHexAddress(entry.Address) {
+			return utils.WrapError(utils.ErrEVMInvalidTokenList, fmt.Errorf("invalid token address %q", entry.Address))
+		}
+
+		address := common.HexToAddress(entry.Address)
+		var tokenAddr utils.Address = &Address{address: address, network: network}
+		keys = append(keys, tokenKey{network: network, address: address})
+		tokens = append(tokens, utils.Token{
+			Type:     utils.ERC20,
+			Address:  &tokenAddr,
+			Name:     entry.Name,
+			Symbol:   entry.Symbol,
+			Decimals: entry.Decimals,
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, key := range keys {
+		r.tokens[key] = tokens[i]
+	}
+	return nil
+}
+
+// LoadTokenListFile seeds the in-memory cache from a token list file.
+func (r *TokenResolver) LoadTokenListFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return r.LoadTokenList(file)
+}
+
+// TokenResolver returns the resolver the Manager resolves token metadata with.
+func (m *Manager) TokenResolver() *TokenResolver {
+	return m.tokenResolver
+}
+
+// ResolveToken returns the ERC20 token at the address with its name, symbol and decimals,
+// reading them from the chain the first time the token is seen on the Manager's network.
+func (m *Manager) ResolveToken(ctx context.Context, address utils.Address) (utils.Token, error) {
+	if address == nil || !common.IsHexAddress(address.String()) {
+		return utils.Token{}, utils.WrapError(utils.ErrEVMInvalidAddress)
+	}
+
+	contract := common.HexToAddress(address.String())
+	token, ok, err := m.tokenResolver.Lookup(m.network, contract)
+	if err != nil {
+		return utils.Token{}, utils.WrapError(utils.ErrEVMFailedToReadToken, err)
+	}
+	if ok {
+		return token, nil
+	}
+
+	token, err = m.GetERC20Token(ctx, address)
+	if err != nil {
+		return utils.Token{}, err
+	}
+	if err := m.tokenResolver.Add(m.network, token); err != nil {
+		return utils.Token{}, utils.WrapError(utils.ErrEVMFailedToStoreToken, err)
+	}
+	return token, nil
+}
+
+// fileToken is the JSON representation of a token in a FileTokenStore.
+type fileToken struct {
+	Network  utils.Blockchain `json:"network"`
+	Address  string           `json:"address"`
+	Name     string           `json:"name"`
+	Symbol   string           `json:"symbol"`
+	Decimals int              `json:"decimals"`
+}
+
+// FileTokenStore is a TokenStore that keeps tokens in a JSON file. The file is rewritten
+// whenever a token is added.
+type FileTokenStore struct {
+	mu     sync.Mutex
+	path   string
+	tokens map[tokenKey]fileToken
+}
+
+// NewFileTokenStore creates a new FileTokenStore instance, loading the tokens already in the
+// file at path. The file is created when the first token is added.
+func NewFileTokenStore(path string) (*FileTokenStore, error) {
+	s := &FileTokenStore{
+		path:   path,
+		tokens: make(map[tokenKey]fileToken),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []fileToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token store %s: %w", path, err)
+	}
+	for _, token := range tokens {
+		if !common.IsHexAddress(token.Address) {
+			return nil, fmt.Errorf("invalid token address %q in token store %s", token.Address, path)
+		}
+		s.tokens[tokenKey{network: token.Network, address: common.HexToAddress(token.Address)}] = token
+	}
+	return s, nil
+}
+
+// Get returns the token stored for the contract address on the network, if any.
+func (s *FileTokenStore) Get(network utils.Blockchain, address common.Address) (utils.Token, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tokens[tokenKey{network: network, address: address}]
+	if !ok {
+		return utils.Token{}, false, nil
+	}
+
+	var tokenAddr utils.Address = &Address{address: address, network: network}
+	return utils.Token{
+		Type:     utils.ERC20,
+		Address:  &tokenAddr,
+		Name:     stored.Name,
+		Symbol:   stored.Symbol,
+		Decimals: stored.Decimals,
+	}, true, nil
+}
+
+// Put stores a token for the network and rewrites the file.
+func (s *FileTokenStore) Put(network utils.Blockchain, token utils.Token) error {
+	address, err := tokenAddress(token, utils.ERC20)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[tokenKey{network: network, address: address}] = fileToken{
+		Network:  network,
+		Address:  address.Hex(),
+		Name:     token.Name,
+		Symbol:   token.Symbol,
+		Decimals: token.Decimals,
+	}
+	return s.write()
+}
+
+// write replaces the file with the stored tokens, going through a temporary file so that
+// a failed write never leaves a truncated store behind.
+func (s *FileTokenStore) write() error {
+	tokens := make([]fileToken, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].Network != tokens[j].Network {
+			return tokens[i].Network < tokens[j].Network
+		}
+		return tokens[i].Address < tokens[j].Address
+	})
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
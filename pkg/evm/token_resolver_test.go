@@ -0,0 +1,155 @@
+package evm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/golang/mock/gomock"
+	mock_evm "github.com/mselser95/blockchain/internal/mock/evm"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTokenList = `{
+	"name": "Test List",
+	"tokens": [
+		{"chainId": 1, "address": "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "name": "USD Coin", "symbol": "USDC", "decimals": 6},
+		{"chainId": 137, "address": "0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359", "name": "USD Coin", "symbol": "USDC", "decimals": 6},
+		{"chainId": 999999, "address": "0x0000000000000000000000000000000000000001", "name": "Unknown", "symbol": "UNK", "decimals": 18}
+	]
+}`
+
+// expectTokenMetadata sets up the calls reading the metadata of a standard token once.
+func expectTokenMetadata(t *testing.T, mockClient *mock_evm.MockClientInterface, name, symbol string, decimals uint8) {
+	mockClient.EXPECT().CallContract(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(tokenContract(t, map[string][]byte{
+			"name()":     packArgs(t, []string{"string"}, name),
+			"symbol()":   packArgs(t, []string{"string"}, symbol),
+			"decimals()": packArgs(t, []string{"uint8"}, decimals),
+		})).Times(3)
+}
+
+// TestManager_ResolveToken tests that token metadata is read from the chain once and then cached.
+// go test -v -cover ./pkg/evm -run TestManager_ResolveToken
+func TestManager_ResolveToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, _ := newTestManager(t, ctrl)
+	address := generateRandomAddress()
+
+	expectTokenMetadata(t, mockClient, "Dai Stablecoin", "DAI", 18)
+
+	// Act
+	first, err := manager.ResolveToken(context.Background(), address)
+	assert.NoError(t, err)
+	second, err := manager.ResolveToken(context.Background(), address)
+	assert.NoError(t, err)
+
+	// Assert
+	for _, token := range []utils.Token{first, second} {
+		assert.Equal(t, utils.ERC20, token.Type)
+		assert.Equal(t, address.String(), (*token.Address).String())
+		assert.Equal(t, "Dai Stablecoin", token.Name)
+		assert.Equal(t, "DAI", token.Symbol)
+		assert.Equal(t, 18, token.Decimals)
+	}
+
+	// The same address on another network is a different token
+	_, ok, err := manager.TokenResolver().Lookup(utils.Polygon, common.HexToAddress(address.String()))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// TestTokenResolver_LoadTokenList tests seeding the resolver from a token list.
+// go test -v -cover ./pkg/evm -run TestTokenResolver_LoadTokenList
+func TestTokenResolver_LoadTokenList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	resolver := evm.NewTokenResolver(nil)
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	assert.NoError(t, os.WriteFile(path, []byte(testTokenList), 0o600))
+	assert.NoError(t, resolver.LoadTokenListFile(path))
+
+	// Seeded tokens are resolved without calling the chain
+	mockClient := mock_evm.NewMockClientInterface(ctrl)
+	mockClientFactory := mock_evm.NewMockClientFactory(ctrl)
+	mockClientFactory.EXPECT().DialContext(gomock.Any(), "http://localhost:8545").Return(mockClient, nil)
+	manager := evm.NewManager("http://localhost:8545", nil, mockClientFactory, utils.Polygon,
+		evm.WithTokenResolver(resolver)).(*evm.Manager)
+	manager.Start(context.Background())
+
+	address, err := evm.NewAddress("0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359", utils.Polygon)
+	assert.NoError(t, err)
+	token, err := manager.ResolveToken(context.Background(), address)
+	assert.NoError(t, err)
+	assert.Equal(t, "USDC", token.Symbol)
+	assert.Equal(t, 6, token.Decimals)
+	assert.Equal(t, string(utils.Polygon), (*token.Address).Network())
+
+	// Tokens are keyed by network
+	_, ok, err := resolver.Lookup(utils.Ethereum, common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	_, ok, err = resolver.Lookup(utils.Polygon, common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Invalid lists are rejected as a whole
+	err = resolver.LoadTokenList(strings.NewReader(`{"tokens": [
+		{"chainId": 1, "address": "0xdAC17F958D2ee523a2206206994597C13D831ec7", "symbol": "USDT", "decimals": 6},
+		{"chainId": 1, "address": "nope"}
+	]}`))
+	assert.ErrorContains(t, err, utils.ErrEVMInvalidTokenList)
+	assert.ErrorContains(t, err, `"nope"`)
+	_, ok, err = resolver.Lookup(utils.Ethereum, common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.ErrorContains(t, resolver.LoadTokenList(strings.NewReader(`not json`)), utils.ErrEVMInvalidTokenList)
+}
+
+// TestFileTokenStore tests that resolved tokens survive a restart through a FileTokenStore.
+// go test -v -cover ./pkg/evm -run TestFileTokenStore
+func TestFileTokenStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	path := filepath.Join(t.TempDir(), "store.json")
+	store, err := evm.NewFileTokenStore(path)
+	assert.NoError(t, err)
+
+	manager, mockClient, _ := newTestManager(t, ctrl)
+	evm.WithTokenResolver(evm.NewTokenResolver(store))(manager)
+	address := generateRandomAddress()
+
+	expectTokenMetadata(t, mockClient, "Wrapped Ether", "WETH", 18)
+	_, err = manager.ResolveToken(context.Background(), address)
+	assert.NoError(t, err)
+
+	// A new store on the same file knows the token
+	reopened, err := evm.NewFileTokenStore(path)
+	assert.NoError(t, err)
+	token, ok, err := reopened.Get(utils.Ethereum, common.HexToAddress(address.String()))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "WETH", token.Symbol)
+	assert.Equal(t, 18, token.Decimals)
+	assert.Equal(t, address.String(), (*token.Address).String())
+
+	// And so does a resolver backed by it, without calling the chain
+	resolved, ok, err := evm.NewTokenResolver(reopened).Lookup(utils.Ethereum, common.HexToAddress(address.String()))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Wrapped Ether", resolved.Name)
+
+	// Corrupt files are reported
+	assert.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = evm.NewFileTokenStore(path)
+	assert.Error(t, err)
+}
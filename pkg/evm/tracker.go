@@ -0,0 +1,404 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/mselser95/blockchain/pkg/utils"
+)
+
+// TrackerEvent identifies what changed in a tracked transaction.
+type TrackerEvent int
+
+const (
+	// TrackerEventIncluded is reported when the transaction is included in a block.
+	TrackerEventIncluded TrackerEvent = iota
+	// TrackerEventConfirmed is reported when the transaction succeeded with enough confirmations.
+	TrackerEventConfirmed
+	// TrackerEventFailed is reported when the transaction reverted with enough confirmations.
+	TrackerEventFailed
+	// TrackerEventRebroadcast is reported when the transaction fell out of the mempool and was sent again.
+	TrackerEventRebroadcast
+	// TrackerEventStuck is reported once when the transaction is still not mined after the stuck timeout.
+	TrackerEventStuck
+	// TrackerEventReplaced is reported when the transaction is replaced through SpeedUpTransaction
+	// or CancelTransaction. The replacement is tracked from then on, and keeps updating the
+	// status of the original utils.Transaction. The transactions it replaced are still watched,
+	// since any of them may be mined instead.
+	TrackerEventReplaced
+	// TrackerEventDropped is reported when the nonce of the transaction was used by another transaction.
+	TrackerEventDropped
+)
+
+// TransactionUpdate describes a change in a tracked transaction.
+type TransactionUpdate struct {
+	Transaction utils.Transaction
+	Hash        string
+	Event       TrackerEvent
+	Status      utils.TransactionStatus
+	BlockNumber uint64
+	ReplacedBy  string // Hash of the replacement, set for TrackerEventReplaced
+}
+
+// TrackerConfig configures the transaction tracker of a Manager.
+type TrackerConfig struct {
+	// PollInterval is how often tracked transactions are checked, 5 seconds by default.
+	PollInterval time.Duration
+	// StuckAfter is how long a transaction can stay unmined before it is flagged as stuck,
+	// 5 minutes by default.
+	StuckAfter time.Duration
+	// Confirmations is the number of confirmations after which a transaction is final, 1 by default.
+	Confirmations uint64
+	// UpdatesBuffer is the capacity of the updates channel, 64 by default.
+	UpdatesBuffer int
+}
+
+// Tracker follows the transactions sent through a Manager until they are final. It is
+// started and stopped along with the Manager.
+type Tracker struct {
+	config TrackerConfig
+	client ClientInterface
+
+	mu        sync.Mutex
+	txs       map[common.Hash]*trackedTransaction
+	callbacks []func(TransactionUpdate)
+	updates   chan TransactionUpdate
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// trackedTransaction holds the tracking state of a single transaction.
+type trackedTransaction struct {
+	tx          utils.Transaction
+	signed      *types.Transaction
+	sentAt      time.Time
+	blockNumber uint64
+	stuck       bool
+	replaced    []*types.Transaction // Other transactions sent at the same nonce
+}
+
+// newTracker creates a new Tracker instance.
+func newTracker(config TrackerConfig) *Tracker {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.StuckAfter <= 0 {
+		config.StuckAfter = 5 * time.Minute
+	}
+	if config.Confirmations == 0 {
+		config.Confirmations = 1
+	}
+	if config.UpdatesBuffer <= 0 {
+		config.UpdatesBuffer = 64
+	}
+
+	return &Tracker{
+		config:  config,
+		txs:     make(map[common.Hash]*trackedTransaction),
+		updates: make(chan TransactionUpdate, config.UpdatesBuffer),
+	}
+}
+
+// OnUpdate registers a callback invoked for every update. Callbacks run on the tracker
+// goroutine and must not block.
+func (t *Tracker) OnUpdate(callback func(TransactionUpdate)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.callbacks = append(t.callbacks, callback)
+}
+
+// Updates returns a channel receiving every update. Updates are dropped when the channel
+// is full, so readers that cannot keep up should use OnUpdate instead.
+func (t *Tracker) Updates() <-chan TransactionUpdate {
+	return t.updates
+}
+
+// Pending returns the hashes of the transactions that are not final yet.
+func (t *Tracker) Pending() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hashes := make([]string, 0, len(t.txs))
+	for hash := range t.txs {
+		hashes = append(hashes, hash.Hex())
+	}
+	return hashes
+}
+
+// start launches the tracking goroutine on the given client.
+func (t *Tracker) start(client ClientInterface) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.client = client
+	t.cancel = cancel
+	t.done = make(chan struct{})
+
+	go t.run(ctx)
+}
+
+// stop stops the tracking goroutine and waits for it to exit.
+func (t *Tracker) stop() {
+	if t.cancel == nil {
+		return
+	}
+	t.cancel()
+	<-t.done
+	t.cancel = nil
+}
+
+// track starts following a transaction that was just broadcast.
+func (t *Tracker) track(tx utils.Transaction, signed *types.Transaction) {
+	tx.SetStatus(utils.Pending)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.txs[signed.Hash()] = &trackedTransaction{tx: tx, signed: signed, sentAt: time.Now()}
+}
+
+// replace follows a replacement transaction instead of the transaction it replaced, keeping
+// the replaced transactions to check whether one of them is mined instead.
+func (t *Tracker) replace(original common.Hash, tx utils.Transaction, signed *types.Transaction) {
+	t.mu.Lock()
+	replaced, ok := t.txs[original]
+	if ok {
+		delete(t.txs, original)
+		t.txs[signed.Hash()] = &trackedTransaction{
+			tx:       replaced.tx,
+			signed:   signed,
+			sentAt:   time.Now(),
+			replaced: append(append([]*types.Transaction{}, replaced.replaced...), replaced.signed),
+		}
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		t.track(tx, signed)
+		return
+	}
+
+	replaced.tx.SetStatus(utils.Pending)
+	t.deliver(TransactionUpdate{
+		Transaction: replaced.tx,
+		Hash:        original.Hex(),
+		Event:       TrackerEventReplaced,
+		Status:      utils.Pending,
+		ReplacedBy:  signed.Hash().Hex(),
+	})
+}
+
+// run checks the tracked transactions on every poll interval until the tracker is stopped.
+func (t *Tracker) run(ctx context.Context) {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.poll(ctx)
+		}
+	}
+}
+
+// poll checks every tracked transaction once.
+func (t *Tracker) poll(ctx context.Context) {
+	t.mu.Lock()
+	tracked := make([]*trackedTransaction, 0, len(t.txs))
+	for _, tx := range t.txs {
+		tracked = append(tracked, tx)
+	}
+	t.mu.Unlock()
+
+	for _, tx := range tracked {
+		if ctx.Err() != nil {
+			return
+		}
+		t.check(ctx, tx)
+	}
+}
+
+// check updates a tracked transaction from the node. Node errors are retried on the next poll.
+func (t *Tracker) check(ctx context.Context, tracked *trackedTransaction) {
+	hash := tracked.signed.Hash()
+	if !t.current(tracked) {
+		return
+	}
+
+	receipt, mined, err := t.receipt(ctx, tracked)
+	if err != nil {
+		return
+	}
+	if receipt != nil {
+		if mined != tracked.signed && !t.follow(tracked, mined) {
+			return
+		}
+		t.checkMined(ctx, tracked, receipt)
+		return
+	}
+
+	// The transaction was reorged out of its block
+	tracked.blockNumber = 0
+
+	// A transaction replaced while it was checked is left to its replacement
+	if !t.current(tracked) {
+		return
+	}
+	if !tracked.stuck && time.Since(tracked.sentAt) >= t.config.StuckAfter {
+		tracked.stuck = true
+		t.notify(tracked, TrackerEventStuck)
+	}
+
+	// Rebroadcast transactions that fell out of the mempool
+	_, _, err = t.client.TransactionByHash(ctx, hash)
+	if !errors.Is(err, ethereum.NotFound) || !t.current(tracked) {
+		return
+	}
+
+	err = t.client.SendTransaction(ctx, tracked.signed)
+	switch {
+	case err == nil || strings.Contains(err.Error(), "already known"):
+		t.notify(tracked, TrackerEventRebroadcast)
+	case strings.Contains(err.Error(), "nonce too low"):
+		// A replaced transaction may have been mined since its receipt was checked
+		if receipt, _, err := t.receipt(ctx, tracked); err != nil || receipt != nil {
+			return
+		}
+		// Another transaction used the nonce, so this one can never be mined
+		t.finish(hash, tracked, utils.Failed, TrackerEventDropped)
+	}
+}
+
+// current reports whether the entry is still tracked under its hash. Entries checked from a
+// poll snapshot go stale when they are replaced meanwhile.
+func (t *Tracker) current(tracked *trackedTransaction) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.txs[tracked.signed.Hash()] == tracked
+}
+
+// receipt returns the receipt of the tracked transaction, or of one of the transactions it
+// replaced, along with the transaction that was mined. Both are nil when none was mined.
+func (t *Tracker) receipt(ctx context.Context, tracked *trackedTransaction) (*types.Receipt, *types.Transaction, error) {
+	t.mu.Lock()
+	candidates := append([]*types.Transaction{tracked.signed}, tracked.replaced...)
+	t.mu.Unlock()
+
+	for _, candidate := range candidates {
+		receipt, err := t.client.TransactionReceipt(ctx, candidate.Hash())
+		if err == nil {
+			return receipt, candidate, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, nil
+}
+
+// follow tracks a replaced transaction that was mined in place of its replacement. It
+// reports false when the tracked transaction was replaced again in the meantime.
+func (t *Tracker) follow(tracked *trackedTransaction, mined *types.Transaction) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hash := tracked.signed.Hash()
+	if t.txs[hash] != tracked {
+		return false
+	}
+	delete(t.txs, hash)
+
+	replaced := []*types.Transaction{tracked.signed}
+	for _, tx := range tracked.replaced {
+		if tx != mined {
+			replaced = append(replaced, tx)
+		}
+	}
+	tracked.signed = mined
+	tracked.replaced = replaced
+	tracked.blockNumber = 0
+	t.txs[mined.Hash()] = tracked
+	return true
+}
+
+// checkMined updates a tracked transaction that has a receipt, finishing it once it has enough confirmations.
+func (t *Tracker) checkMined(ctx context.Context, tracked *trackedTransaction, receipt *types.Receipt) {
+	blockNumber := receipt.BlockNumber.Uint64()
+	if tracked.blockNumber != blockNumber {
+		tracked.blockNumber = blockNumber
+		tracked.tx.SetBlockNumber(blockNumber)
+		t.notify(tracked, TrackerEventIncluded)
+	}
+
+	head, err := t.client.BlockNumber(ctx)
+	if err != nil || head < blockNumber || head-blockNumber+1 < t.config.Confirmations {
+		return
+	}
+
+	if receipt.Status == types.ReceiptStatusFailed {
+		t.finish(tracked.signed.Hash(), tracked, utils.Failed, TrackerEventFailed)
+		return
+	}
+	t.finish(tracked.signed.Hash(), tracked, utils.Confirmed, TrackerEventConfirmed)
+}
+
+// finish sets the final status of a transaction and stops tracking it, unless the entry went
+// stale because the transaction was replaced.
+func (t *Tracker) finish(hash common.Hash, tracked *trackedTransaction, status utils.TransactionStatus, event TrackerEvent) {
+	t.mu.Lock()
+	if t.txs[hash] != tracked {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.txs, hash)
+	t.mu.Unlock()
+
+	tracked.tx.SetStatus(status)
+	t.notify(tracked, event)
+}
+
+// notify delivers an update about a tracked transaction.
+func (t *Tracker) notify(tracked *trackedTransaction, event TrackerEvent) {
+	update := TransactionUpdate{
+		Transaction: tracked.tx,
+		Hash:        tracked.signed.Hash().Hex(),
+		Event:       event,
+		BlockNumber: tracked.blockNumber,
+	}
+	if status := tracked.tx.Status(); status != nil {
+		update.Status = *status
+	}
+	t.deliver(update)
+}
+
+// deliver sends an update to the callbacks and the updates channel.
+func (t *Tracker) deliver(update TransactionUpdate) {
+	t.mu.Lock()
+	callbacks := append([]func(TransactionUpdate){}, t.callbacks...)
+	t.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(update)
+	}
+
+	select {
+	case t.updates <- update:
+	default:
+	}
+}
+
+// Tracker returns the transaction tracker of the Manager, or nil when it was not enabled with WithTracker.
+func (m *Manager) Tracker() *Tracker {
+	return m.tracker
+}
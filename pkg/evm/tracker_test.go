@@ -0,0 +1,314 @@
+package evm_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/mock/gomock"
+	"github.com/mselser95/blockchain/pkg/evm"
+	"github.com/mselser95/blockchain/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTrackedTransfer returns a transfer from the sender with every field set.
+func newTrackedTransfer(from utils.Address) utils.Transaction {
+	txType := utils.Transfer
+	return evm.NewTransaction(nil, from, generateRandomAddress(), big.NewInt(1000), &txType, nil, nil, nil,
+		21000, big.NewInt(1), big.NewInt(1), 3, nil)
+}
+
+// waitForUpdate reads updates until one with the given event arrives.
+func waitForUpdate(t *testing.T, updates <-chan evm.TransactionUpdate, event evm.TrackerEvent) evm.TransactionUpdate {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case update := <-updates:
+			if update.Event == event {
+				return update
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for tracker event %d", event)
+		}
+	}
+}
+
+// TestTracker_Lifecycle tests that a sent transaction is rebroadcast when it leaves the mempool and followed until confirmed.
+// go test -v -cover ./pkg/evm -run TestTracker_Lifecycle
+func TestTracker_Lifecycle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl, evm.WithTracker(evm.TrackerConfig{PollInterval: time.Millisecond, Confirmations: 2}))
+	tx := newTrackedTransfer(from)
+	defer func() {
+		mockClient.EXPECT().Close()
+		assert.NoError(t, manager.Stop(context.Background()))
+	}()
+
+	var sent []*types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = append(sent, tx)
+			return nil
+		}).Times(2)
+
+	// The transaction falls out of the mempool once, then gets mined in block 5
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(5)}
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), gomock.Any()).Return(nil, ethereum.NotFound).Times(1)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), gomock.Any()).Return(nil, false, ethereum.NotFound).Times(1)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), gomock.Any()).Return(receipt, nil).AnyTimes()
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(5), nil).Times(1)
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(6), nil).AnyTimes()
+
+	// Act
+	hash, err := manager.SendTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+
+	// Assert
+	updates := manager.Tracker().Updates()
+	rebroadcast := waitForUpdate(t, updates, evm.TrackerEventRebroadcast)
+	assert.Equal(t, hash, rebroadcast.Hash)
+
+	included := waitForUpdate(t, updates, evm.TrackerEventIncluded)
+	assert.Equal(t, uint64(5), included.BlockNumber)
+	assert.Equal(t, utils.Pending, included.Status)
+
+	confirmed := waitForUpdate(t, updates, evm.TrackerEventConfirmed)
+	assert.Equal(t, utils.Confirmed, confirmed.Status)
+	assert.Equal(t, tx, confirmed.Transaction)
+	assert.Equal(t, utils.Confirmed, *tx.Status())
+	assert.Equal(t, uint64(5), *tx.BlockNumber())
+	assert.Empty(t, manager.Tracker().Pending())
+
+	// The same signed transaction was rebroadcast
+	assert.Len(t, sent, 2)
+	assert.Equal(t, sent[0].Hash(), sent[1].Hash())
+}
+
+// TestTracker_StuckAndDropped tests that unmined transactions are flagged as stuck and dropped once their nonce is used.
+// go test -v -cover ./pkg/evm -run TestTracker_StuckAndDropped
+func TestTracker_StuckAndDropped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl, evm.WithTracker(evm.TrackerConfig{
+		PollInterval: time.Millisecond,
+		StuckAfter:   time.Millisecond,
+	}))
+	tx := newTrackedTransfer(from)
+	defer func() {
+		mockClient.EXPECT().Close()
+		assert.NoError(t, manager.Stop(context.Background()))
+	}()
+
+	events := make(chan evm.TransactionUpdate, 10)
+	manager.Tracker().OnUpdate(func(update evm.TransactionUpdate) {
+		events <- update
+	})
+
+	// The transaction stays in the mempool for a while, then another transaction takes its nonce
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), gomock.Any()).Return(nil, ethereum.NotFound).AnyTimes()
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), gomock.Any()).Return(nil, true, nil).Times(2)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), gomock.Any()).Return(nil, false, ethereum.NotFound).Times(1)
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).Return(errors.New("nonce too low")).Times(1)
+
+	// Act
+	_, err := manager.SendTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+
+	// Assert
+	stuck := waitForUpdate(t, events, evm.TrackerEventStuck)
+	assert.Equal(t, utils.Pending, stuck.Status)
+
+	dropped := waitForUpdate(t, events, evm.TrackerEventDropped)
+	assert.Equal(t, utils.Failed, dropped.Status)
+	assert.Equal(t, utils.Failed, *tx.Status())
+	assert.Empty(t, manager.Tracker().Pending())
+}
+
+// TestTracker_Replaced tests that a replacement is tracked in place of the transaction it replaced.
+// go test -v -cover ./pkg/evm -run TestTracker_Replaced
+func TestTracker_Replaced(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Poll slowly so the tracker does not interfere with the replacement
+	manager, mockClient, from := newTestManager(t, ctrl, evm.WithTracker(evm.TrackerConfig{PollInterval: time.Hour}))
+	tx := newTrackedTransfer(from)
+	defer func() {
+		mockClient.EXPECT().Close()
+		assert.NoError(t, manager.Stop(context.Background()))
+	}()
+
+	var sent []*types.Transaction
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			sent = append(sent, tx)
+			return nil
+		}).Times(2)
+
+	hash, err := manager.SendTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), sent[0].Hash()).Return(sent[0], true, nil)
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(nil, errors.New("method not found"))
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), nil).Return(&types.Header{}, nil)
+	mockClient.EXPECT().SuggestGasPrice(gomock.Any()).Return(big.NewInt(1), nil)
+
+	// Act
+	replacement, err := manager.SpeedUpTransaction(context.Background(), hash)
+	assert.NoError(t, err)
+
+	// Assert
+	replaced := waitForUpdate(t, manager.Tracker().Updates(), evm.TrackerEventReplaced)
+	assert.Equal(t, hash, replaced.Hash)
+	assert.Equal(t, replacement, replaced.ReplacedBy)
+	assert.Equal(t, tx, replaced.Transaction)
+	assert.Equal(t, []string{replacement}, manager.Tracker().Pending())
+}
+
+// TestTracker_ReplacedOriginalMined tests that a replaced transaction mined instead of its replacement is followed to the end.
+// go test -v -cover ./pkg/evm -run TestTracker_ReplacedOriginalMined
+func TestTracker_ReplacedOriginalMined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl, evm.WithTracker(evm.TrackerConfig{PollInterval: time.Millisecond}))
+	tx := newTrackedTransfer(from)
+	defer func() {
+		mockClient.EXPECT().Close()
+		assert.NoError(t, manager.Stop(context.Background()))
+	}()
+
+	var original, mined atomic.Pointer[types.Transaction]
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			original.CompareAndSwap(nil, tx)
+			return nil
+		}).Times(2)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, common.Hash) (*types.Transaction, bool, error) {
+			return original.Load(), true, nil
+		}).AnyTimes()
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+			if tx := mined.Load(); tx != nil && tx.Hash() == hash {
+				return &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(5)}, nil
+			}
+			return nil, ethereum.NotFound
+		}).AnyTimes()
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(5), nil).AnyTimes()
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(nil, errors.New("method not found"))
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), nil).Return(&types.Header{}, nil)
+	mockClient.EXPECT().SuggestGasPrice(gomock.Any()).Return(big.NewInt(1), nil)
+
+	events := make(chan evm.TransactionUpdate, 10)
+	manager.Tracker().OnUpdate(func(update evm.TransactionUpdate) {
+		events <- update
+	})
+
+	hash, err := manager.SendTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+	replacement, err := manager.SpeedUpTransaction(context.Background(), hash)
+	assert.NoError(t, err)
+
+	// Act
+	mined.Store(original.Load())
+
+	// Assert
+	included := waitForUpdate(t, events, evm.TrackerEventIncluded)
+	assert.Equal(t, hash, included.Hash)
+
+	confirmed := waitForUpdate(t, events, evm.TrackerEventConfirmed)
+	assert.Equal(t, hash, confirmed.Hash)
+	assert.NotEqual(t, replacement, confirmed.Hash)
+	assert.Equal(t, utils.Confirmed, *tx.Status())
+	assert.Empty(t, manager.Tracker().Pending())
+}
+
+// TestTracker_ReplacedWhileChecked tests that a transaction replaced while it is checked is left to its replacement.
+// go test -v -cover ./pkg/evm -run TestTracker_ReplacedWhileChecked
+func TestTracker_ReplacedWhileChecked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manager, mockClient, from := newTestManager(t, ctrl, evm.WithTracker(evm.TrackerConfig{PollInterval: time.Millisecond}))
+	tx := newTrackedTransfer(from)
+	defer func() {
+		mockClient.EXPECT().Close()
+		assert.NoError(t, manager.Stop(context.Background()))
+	}()
+
+	var original, replacement atomic.Pointer[types.Transaction]
+	var replaced atomic.Bool
+	var checked sync.Once
+	checking, release := make(chan struct{}), make(chan struct{})
+	mockClient.EXPECT().SendTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, tx *types.Transaction) error {
+			if !original.CompareAndSwap(nil, tx) && !replacement.CompareAndSwap(nil, tx) {
+				return errors.New("nonce too low")
+			}
+			return nil
+		}).MinTimes(2)
+	mockClient.EXPECT().TransactionByHash(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, common.Hash) (*types.Transaction, bool, error) {
+			if replaced.Load() {
+				return nil, false, ethereum.NotFound
+			}
+			return original.Load(), true, nil
+		}).AnyTimes()
+	mockClient.EXPECT().TransactionReceipt(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+			if tx := replacement.Load(); tx != nil && tx.Hash() == hash && replaced.Load() {
+				return &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(5)}, nil
+			}
+			// The first check of the original waits for the replacement to be sent
+			checked.Do(func() {
+				close(checking)
+				<-release
+			})
+			return nil, ethereum.NotFound
+		}).AnyTimes()
+	mockClient.EXPECT().BlockNumber(gomock.Any()).Return(uint64(5), nil).AnyTimes()
+	mockClient.EXPECT().FeeHistory(gomock.Any(), gomock.Any(), nil, gomock.Any()).Return(nil, errors.New("method not found"))
+	mockClient.EXPECT().HeaderByNumber(gomock.Any(), nil).Return(&types.Header{}, nil)
+	mockClient.EXPECT().SuggestGasPrice(gomock.Any()).Return(big.NewInt(1), nil)
+
+	var staleUpdate atomic.Bool
+	events := make(chan evm.TransactionUpdate, 10)
+	manager.Tracker().OnUpdate(func(update evm.TransactionUpdate) {
+		if update.Event == evm.TrackerEventDropped || update.Event == evm.TrackerEventRebroadcast {
+			staleUpdate.Store(true)
+		}
+		events <- update
+	})
+
+	hash, err := manager.SendTransaction(context.Background(), tx)
+	assert.NoError(t, err)
+	<-checking
+
+	// Act: the replacement is sent and mined while the original is checked
+	_, err = manager.SpeedUpTransaction(context.Background(), hash)
+	assert.NoError(t, err)
+	replaced.Store(true)
+	close(release)
+
+	// Assert
+	waitForUpdate(t, events, evm.TrackerEventReplaced)
+	confirmed := waitForUpdate(t, events, evm.TrackerEventConfirmed)
+	assert.Equal(t, replacement.Load().Hash().Hex(), confirmed.Hash)
+	assert.Equal(t, utils.Confirmed, *tx.Status())
+	assert.Empty(t, manager.Tracker().Pending())
+
+	// The stale entry of the original neither rebroadcast it nor reported it dropped
+	assert.False(t, staleUpdate.Load())
+}
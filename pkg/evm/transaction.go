@@ -2,9 +2,12 @@ package evm
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/mselser95/blockchain/pkg/utils"
 )
 
@@ -70,6 +73,39 @@ func NewTransaction(
 	return tx
 }
 
+// NewDynamicFeeTransaction creates a new instance of BaseTransaction priced with EIP-1559
+// dynamic fees instead of a legacy gas price.
+func NewDynamicFeeTransaction(
+	txHash *utils.TxHash,
+	from utils.Address,
+	to utils.Address,
+	amount *big.Int,
+	txType *utils.TransactionType,
+	status *utils.TransactionStatus,
+	timestamp *time.Time,
+	blockNumber *uint64,
+	gasLimit uint64,
+	maxPriorityFeePerGas *big.Int,
+	maxFeePerGas *big.Int,
+	chainId *big.Int,
+	nonce uint64,
+	data []byte,
+) utils.Transaction {
+	tx := NewTransaction(
+		txHash, from, to, amount, txType, status, timestamp, blockNumber,
+		gasLimit, nil, chainId, nonce, data,
+	)
+
+	if maxPriorityFeePerGas != nil {
+		tx.SetPayload("maxPriorityFeePerGas", maxPriorityFeePerGas)
+	}
+	if maxFeePerGas != nil {
+		tx.SetPayload("maxFeePerGas", maxFeePerGas)
+	}
+
+	return tx
+}
+
 // Hash returns the transaction ID or hash.
 func (t *BaseTransaction) Hash() *utils.TxHash {
 	return t.TxHash
@@ -126,11 +162,22 @@ func (t *BaseTransaction) Validate() error {
 		return errors.New("missing sender address")
 	}
 
-	if t.To() == nil || t.To().String() == "" {
+	// Contract deployments are the only transactions without a recipient
+	if t.Type() != nil && *t.Type() == utils.Deploy {
+		if t.To() != nil && t.To().String() != "" {
+			return errors.New("deploy transaction cannot have a recipient")
+		}
+		if bytecode, _ := t.TxPayload["data"].([]byte); len(bytecode) == 0 {
+			return errors.New("deploy transaction requires the contract bytecode in payload 'data'")
+		}
+		if _, ok := t.TxPayload["blobs"]; ok {
+			return errors.New("blob transactions cannot deploy contracts")
+		}
+	} else if t.To() == nil || t.To().String() == "" {
 		return errors.New("missing recipient address")
 	}
 
-	if t.Amount() == nil || t.Amount().Sign() <= 0 {
+	if t.Amount() == nil || t.Amount().Sign() < 0 {
 		return errors.New("invalid transaction amount")
 	}
 
@@ -141,12 +188,18 @@ func (t *BaseTransaction) Validate() error {
 		}
 	}
 
-	if gasPrice, ok := t.TxPayload["gasPrice"]; ok {
-		if _, ok := gasPrice.(*big.Int); !ok {
-			return errors.New("payload 'gasPrice' must be *big.Int")
+	if err := t.validateFees(); err != nil {
+		return err
+	}
+
+	if accessList, ok := t.TxPayload["accessList"]; ok {
+		if _, ok := accessList.(types.AccessList); !ok {
+			return errors.New("payload 'accessList' must be types.AccessList")
 		}
-	} else {
-		return errors.New("missing payload 'gasPrice'")
+	}
+
+	if err := t.validateBlobs(); err != nil {
+		return err
 	}
 
 	if gasLimit, ok := t.TxPayload["gasLimit"]; ok {
@@ -176,6 +229,78 @@ func (t *BaseTransaction) Validate() error {
 	return nil
 }
 
+// validateFees checks that the payload prices the transaction with either a legacy
+// gas price or EIP-1559 dynamic fees, but not both.
+func (t *BaseTransaction) validateFees() error {
+	gasPrice, hasGasPrice := t.TxPayload["gasPrice"]
+	maxFee, hasMaxFee := t.TxPayload["maxFeePerGas"]
+	maxTip, hasMaxTip := t.TxPayload["maxPriorityFeePerGas"]
+
+	if !hasMaxFee && !hasMaxTip {
+		if !hasGasPrice {
+			return errors.New("missing payload 'gasPrice'")
+		}
+		if _, ok := gasPrice.(*big.Int); !ok {
+			return errors.New("payload 'gasPrice' must be *big.Int")
+		}
+		return nil
+	}
+
+	if hasGasPrice {
+		return errors.New("payload cannot set both 'gasPrice' and dynamic fee fields")
+	}
+	if !hasMaxFee {
+		return errors.New("missing payload 'maxFeePerGas'")
+	}
+	if !hasMaxTip {
+		return errors.New("missing payload 'maxPriorityFeePerGas'")
+	}
+
+	feeCap, ok := maxFee.(*big.Int)
+	if !ok {
+		return errors.New("payload 'maxFeePerGas' must be *big.Int")
+	}
+	tipCap, ok := maxTip.(*big.Int)
+	if !ok {
+		return errors.New("payload 'maxPriorityFeePerGas' must be *big.Int")
+	}
+	if tipCap.Cmp(feeCap) > 0 {
+		return errors.New("payload 'maxPriorityFeePerGas' cannot exceed 'maxFeePerGas'")
+	}
+
+	return nil
+}
+
+// validateBlobs checks the EIP-4844 payload fields of a blob transaction.
+func (t *BaseTransaction) validateBlobs() error {
+	rawBlobs, ok := t.TxPayload["blobs"]
+	if !ok {
+		return nil
+	}
+
+	blobs, ok := rawBlobs.([]kzg4844.Blob)
+	if !ok {
+		return errors.New("payload 'blobs' must be []kzg4844.Blob")
+	}
+	if len(blobs) == 0 || len(blobs) > MaxBlobsPerTransaction {
+		return fmt.Errorf("payload 'blobs' must contain between 1 and %d blobs", MaxBlobsPerTransaction)
+	}
+
+	if blobFeeCap, ok := t.TxPayload["maxFeePerBlobGas"]; ok {
+		if _, ok := blobFeeCap.(*big.Int); !ok {
+			return errors.New("payload 'maxFeePerBlobGas' must be *big.Int")
+		}
+	} else {
+		return errors.New("missing payload 'maxFeePerBlobGas'")
+	}
+
+	if _, ok := t.TxPayload["maxFeePerGas"]; !ok {
+		return errors.New("blob transactions require dynamic fee fields")
+	}
+
+	return nil
+}
+
 // SetStatus updates the transaction status.
 func (t *BaseTransaction) SetStatus(status utils.TransactionStatus) {
 	t.TxStatus = &status
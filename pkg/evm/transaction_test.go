@@ -152,6 +152,32 @@ func TestBaseTransaction_Validate_InvalidAmount(t *testing.T) {
 	assert.Equal(t, "invalid transaction amount", err.Error())
 }
 
+func TestBaseTransaction_Validate_ZeroAndNegativeAmount(t *testing.T) {
+	// Arrange
+	gasPrice := big.NewInt(50)
+	gasLimit := uint64(21000)
+	nonce := uint64(1)
+	chainId := big.NewInt(1)
+	fromAddress := generateRandomAddress()
+	txType := utils.Transfer
+
+	// A zero-value self-transfer, as used to cancel a pending transaction
+	zeroTx := evm.NewTransaction(
+		nil, fromAddress, fromAddress, big.NewInt(0),
+		&txType, nil, nil, nil,
+		gasLimit, gasPrice, chainId, nonce, nil,
+	)
+	negativeTx := evm.NewTransaction(
+		nil, fromAddress, generateRandomAddress(), big.NewInt(-1),
+		&txType, nil, nil, nil,
+		gasLimit, gasPrice, chainId, nonce, nil,
+	)
+
+	// Act & Assert
+	assert.NoError(t, zeroTx.Validate())
+	assert.EqualError(t, negativeTx.Validate(), "invalid transaction amount")
+}
+
 func TestBaseTransaction_Validate_MissingGasPrice(t *testing.T) {
 	// Arrange
 	gasLimit := uint64(21000)
@@ -404,3 +430,74 @@ func TestBaseTransaction_Validate_InvalidData(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "payload 'data' must be a []byte", err.Error())
 }
+
+func TestBaseTransaction_Validate_DynamicFee(t *testing.T) {
+	// Arrange
+	txType := utils.Transfer
+	tx := evm.NewDynamicFeeTransaction(
+		nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(100),
+		&txType, nil, nil, nil,
+		21000, big.NewInt(2), big.NewInt(100), big.NewInt(1), 1, nil,
+	)
+
+	// Act
+	err := tx.Validate()
+
+	// Assert
+	assert.NoError(t, err)
+	_, hasGasPrice := tx.Payload()["gasPrice"]
+	assert.False(t, hasGasPrice)
+}
+
+func TestBaseTransaction_Validate_DynamicFeeMissingTip(t *testing.T) {
+	// Arrange
+	txType := utils.Transfer
+	tx := evm.NewDynamicFeeTransaction(
+		nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(100),
+		&txType, nil, nil, nil,
+		21000, nil, big.NewInt(100), big.NewInt(1), 1, nil,
+	)
+
+	// Act
+	err := tx.Validate()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, "missing payload 'maxPriorityFeePerGas'", err.Error())
+}
+
+func TestBaseTransaction_Validate_TipAboveFeeCap(t *testing.T) {
+	// Arrange
+	txType := utils.Transfer
+	tx := evm.NewDynamicFeeTransaction(
+		nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(100),
+		&txType, nil, nil, nil,
+		21000, big.NewInt(200), big.NewInt(100), big.NewInt(1), 1, nil,
+	)
+
+	// Act
+	err := tx.Validate()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, "payload 'maxPriorityFeePerGas' cannot exceed 'maxFeePerGas'", err.Error())
+}
+
+func TestBaseTransaction_Validate_MixedFeeModels(t *testing.T) {
+	// Arrange
+	txType := utils.Transfer
+	tx := evm.NewTransaction(
+		nil, generateRandomAddress(), generateRandomAddress(), big.NewInt(100),
+		&txType, nil, nil, nil,
+		21000, big.NewInt(50), big.NewInt(1), 1, nil,
+	)
+	tx.SetPayload("maxFeePerGas", big.NewInt(100))
+	tx.SetPayload("maxPriorityFeePerGas", big.NewInt(2))
+
+	// Act
+	err := tx.Validate()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, "payload cannot set both 'gasPrice' and dynamic fee fields", err.Error())
+}
@@ -16,4 +16,6 @@ type BlockchainManager interface {
 	ReadCall(ctx context.Context, tx utils.Transaction) (interface{}, error)
 	SendTransaction(ctx context.Context, tx utils.Transaction) (string, error)
 	GetTransactionDetails(ctx context.Context, txID string) (*utils.TransactionDetails, error)
+	WaitForConfirmation(ctx context.Context, txID string, confirmations uint64) (*utils.TransactionDetails, error)
+	SubscribeEvents(ctx context.Context, filter utils.EventFilter) (<-chan utils.Log, utils.Subscription, error)
 }
@@ -65,4 +65,70 @@ var (
 
 	// ErrEVMInvalidHash is returned when a hash is invalid.
 	ErrEVMInvalidHash = "invalid hash"
+
+	// ErrEVMExecutionReverted is returned when a call or gas estimation reverts.
+	ErrEVMExecutionReverted = "execution reverted"
+
+	// ErrEVMFailedToEstimateGas is returned when gas estimation fails for reasons other than a revert.
+	ErrEVMFailedToEstimateGas = "failed to estimate gas"
+
+	// ErrEVMFailedToCallContract is returned when a contract call fails for reasons other than a revert.
+	ErrEVMFailedToCallContract = "failed to call contract"
+
+	// ErrEVMFailedToUnpackOutput is returned when the output of a contract call cannot be decoded.
+	ErrEVMFailedToUnpackOutput = "failed to unpack call output"
+
+	// ErrEVMFailedToCreateAccessList is returned when an access list cannot be generated.
+	ErrEVMFailedToCreateAccessList = "failed to create access list"
+
+	// ErrEVMFailedToPrepareTransaction is returned when the missing fields of a transaction cannot be filled in.
+	ErrEVMFailedToPrepareTransaction = "failed to prepare transaction"
+
+	// ErrEVMFailedToSuggestFees is returned when transaction fees cannot be suggested.
+	ErrEVMFailedToSuggestFees = "failed to suggest fees"
+
+	// ErrEVMTransactionNotPending is returned when a transaction that is no longer pending is replaced.
+	ErrEVMTransactionNotPending = "transaction is not pending"
+
+	// ErrEVMFailedToReplaceTransaction is returned when a replacement transaction cannot be built.
+	ErrEVMFailedToReplaceTransaction = "failed to replace transaction"
+
+	// ErrEVMTransactionDropped is returned when a transaction was dropped before being mined.
+	ErrEVMTransactionDropped = "transaction dropped"
+
+	// ErrEVMTransactionReverted is returned when a mined transaction reverted.
+	ErrEVMTransactionReverted = "transaction reverted"
+
+	// ErrEVMFailedToWaitForConfirmation is returned when waiting for a transaction is interrupted.
+	ErrEVMFailedToWaitForConfirmation = "failed to wait for confirmation"
+
+	// ErrEVMFailedToSubscribe is returned when an event subscription cannot be started.
+	ErrEVMFailedToSubscribe = "failed to subscribe to events"
+
+	// ErrEVMFailedToFilterLogs is returned when historical logs cannot be queried.
+	ErrEVMFailedToFilterLogs = "failed to filter logs"
+
+	// ErrEVMInvalidToken is returned when a token is not a valid token of the expected standard.
+	ErrEVMInvalidToken = "invalid token"
+
+	// ErrEVMTokenOperationRejected is returned when a token contract returns false for an operation.
+	ErrEVMTokenOperationRejected = "token rejected the operation"
+
+	// ErrEVMFailedToReadToken is returned when the state or metadata of a token cannot be read.
+	ErrEVMFailedToReadToken = "failed to read token"
+
+	// ErrEVMFailedToStoreToken is returned when resolved token metadata cannot be persisted.
+	ErrEVMFailedToStoreToken = "failed to store token"
+
+	// ErrEVMInvalidTokenList is returned when a token list cannot be decoded or has an invalid entry.
+	ErrEVMInvalidTokenList = "invalid token list"
+
+	// ErrEVMFailedToAggregateCalls is returned when calls aggregated through Multicall3 fail.
+	ErrEVMFailedToAggregateCalls = "failed to aggregate calls"
+
+	// ErrEVMNoHealthyEndpoint is returned when none of the endpoints of a failover client can be used.
+	ErrEVMNoHealthyEndpoint = "no healthy endpoint"
+
+	// ErrEVMRateLimited is returned when a call gives up waiting for the request budget of its endpoint.
+	ErrEVMRateLimited = "rate limited"
 )
@@ -0,0 +1,18 @@
+package utils
+
+// EventFilter selects the logs streamed by an event subscription or returned by a log query.
+type EventFilter struct {
+	Addresses []Address  // Contracts whose logs are streamed, all contracts when empty
+	Topics    [][]string // Topics by position, where an empty position matches any topic
+	FromBlock *uint64    // First block to stream or query, the next block or genesis when nil
+	ToBlock   *uint64    // Last block to query, the latest block when nil; ignored by subscriptions
+}
+
+// Subscription is a handle to an event stream.
+type Subscription interface {
+	// Unsubscribe stops the stream and closes its log channel.
+	Unsubscribe()
+
+	// Err returns a channel that receives the error ending the stream, if any. It is closed on Unsubscribe.
+	Err() <-chan error
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "math/big"
+
 // TokenType is an enumeration of supported token types.
 type TokenType int
 
@@ -12,11 +14,15 @@ const (
 	SPLToken
 	// CosmosDenom represents a Cosmos denomination on the Cosmos network.
 	CosmosDenom
+	// ERC721 represents an ERC721 non-fungible token on the Ethereum network.
+	ERC721
+	// ERC1155 represents an ERC1155 multi-token on the Ethereum network.
+	ERC1155
 )
 
 // Token represents an abstract token on a blockchain network.
 type Token struct {
-	// Type of the token (e.g., Native, ERC20, SPLToken, CosmosDenom)
+	// Type of the token (e.g., Native, ERC20, ERC721, ERC1155, SPLToken, CosmosDenom)
 	Type TokenType
 	// Address or identifier of the token (contract address, mint address, denomination)
 	Address *Address
@@ -26,4 +32,6 @@ type Token struct {
 	Symbol string
 	// Number of decimals used by the token
 	Decimals int
+	// ID of the token within its contract, for ERC721 and ERC1155 tokens
+	ID *big.Int
 }
@@ -17,6 +17,8 @@ const (
 	Stake
 	// Delegate represents a transaction that delegates tokens.
 	Delegate
+	// Deploy represents a transaction that deploys a smart contract.
+	Deploy
 )
 
 // TransactionStatus is an enumeration of possible transaction statuses.
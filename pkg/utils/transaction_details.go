@@ -8,6 +8,7 @@ import (
 // Event represents a generic blockchain event.
 type Event struct {
 	Name   string                 // Name of the event (e.g., Transfer, Approval)
+	Addr   Address                // Address of the contract that emitted the event
 	Params map[string]interface{} // Parameters associated with the event
 }
 
@@ -19,18 +20,23 @@ type Log struct {
 	BlockNumber uint64   // Block number where this log was included
 	TxHash      TxHash   // Hash of the transaction this log was part of
 	Index       uint     // Index of the log within the block
+	Removed     bool     // Whether the log was reverted by a chain reorganization
 }
 
 // TransactionDetails represents the details of a blockchain transaction.
 type TransactionDetails struct {
-	Hash        TxHash                 // Transaction ID or hash
-	Status      TransactionStatus      // Status of the transaction (e.g., "pending", "confirmed", "failed")
-	BlockNumber uint64                 // Block number where the transaction was included
-	Timestamp   time.Time              // Timestamp of the transaction
-	From        Address                // Sender address
-	To          Address                // Receiver address
-	Amount      *big.Int               // Amount transferred
-	Fee         *big.Int               // Transaction fee
-	Logs        []Log                  // Logs generated by the transaction
-	Events      map[string]interface{} // Generic events associated with the transaction
+	Hash            TxHash                 // Transaction ID or hash
+	Status          TransactionStatus      // Status of the transaction (e.g., "pending", "confirmed", "failed")
+	BlockNumber     uint64                 // Block number where the transaction was included
+	Timestamp       time.Time              // Timestamp of the transaction
+	From            Address                // Sender address
+	To              Address                // Receiver address, nil for contract deployments
+	ContractAddress Address                // Address of the contract created by the transaction, if any
+	Amount          *big.Int               // Amount transferred
+	Fee             *big.Int               // Transaction fee
+	BlobGasUsed     uint64                 // Blob gas used by the transaction, if it carried blobs
+	BlobFee         *big.Int               // Fee paid for blob gas, if the transaction carried blobs
+	Logs            []Log                  // Logs generated by the transaction
+	Events          map[string]interface{} // Event parameters by name, or by topic0 when undecoded; repeats get a "#n" suffix
+	DecodedEvents   []Event                // Decoded events emitted by the transaction, in log order
 }